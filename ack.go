@@ -0,0 +1,105 @@
+package rediswatcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	rds "github.com/redis/go-redis/v9"
+)
+
+// Ack is published by a watcher on the ReplyTo channel of a message it
+// handled, see UpdateAndWait.
+type Ack struct {
+	MessageID string `json:"messageID"`
+	ID        string `json:"id"`
+}
+
+// ackChannel is the channel the acknowledgements of the message messageID
+// are published on. It shares the hash tag of a sharded channel, so it is
+// served by the same shard.
+func ackChannel(channel, messageID string) string {
+	return channel + ":ack:" + messageID
+}
+
+// UpdateAndWait publishes an Update like Update and blocks until quorum
+// other watchers acknowledged that they handled it, or ctx is done. Watchers
+// acknowledge a message once the update callback or the typed handler for
+// it returned, so set a deadline on ctx to bound the wait.
+func (w *Watcher) UpdateAndWait(ctx context.Context, quorum int) error {
+	messageID := uuid.New().String()
+	sub, err := w.subscribeAcks(ctx, ackChannel(w.options.Channel, messageID))
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	err = w.logRecord(func() error {
+		w.l.Lock()
+		defer w.l.Unlock()
+		return w.publish(MSG{
+			Method:    MethodUpdate,
+			MessageID: messageID,
+			ReplyTo:   ackChannel(w.options.Channel, messageID),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	acked := make(map[string]bool)
+	for len(acked) < quorum {
+		select {
+		case m, ok := <-sub.Channel():
+			if !ok {
+				return fmt.Errorf("acknowledgements of %s: subscription closed after %d of %d", messageID, len(acked), quorum)
+			}
+			var ack Ack
+			if err := json.Unmarshal([]byte(m.Payload), &ack); err != nil || ack.MessageID != messageID {
+				continue
+			}
+			acked[ack.ID] = true
+		case <-ctx.Done():
+			return fmt.Errorf("acknowledgements of %s: received %d of %d: %w", messageID, len(acked), quorum, ctx.Err())
+		}
+	}
+	return nil
+}
+
+// subscribeAcks subscribes to channel and waits for the subscription to be
+// confirmed, so no acknowledgement is missed.
+func (w *Watcher) subscribeAcks(ctx context.Context, channel string) (*rds.PubSub, error) {
+	_, client := w.clients()
+	var sub *rds.PubSub
+	if w.options.ShardedPubSub {
+		sub = client.SSubscribe(ctx, channel)
+	} else {
+		sub = client.Subscribe(ctx, channel)
+	}
+	if _, err := sub.Receive(ctx); err != nil {
+		_ = sub.Close()
+		return nil, err
+	}
+	return sub, nil
+}
+
+// ack acknowledges msg on its ReplyTo channel, if it asks for it.
+func (w *Watcher) ack(msg *MSG) {
+	if msg == nil || msg.ReplyTo == "" || msg.ID == w.options.LocalID {
+		return
+	}
+	data, err := json.Marshal(Ack{MessageID: msg.MessageID, ID: w.options.LocalID})
+	if err != nil {
+		w.reportError(err)
+		return
+	}
+	if w.options.ShardedPubSub {
+		err = w.pubClient.SPublish(w.ctx, msg.ReplyTo, data).Err()
+	} else {
+		err = w.pubClient.Publish(w.ctx, msg.ReplyTo, data).Err()
+	}
+	if err != nil {
+		w.reportError(err)
+	}
+}
@@ -0,0 +1,203 @@
+package rediswatcher
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	rds "github.com/redis/go-redis/v9"
+)
+
+// addRules adds the rules in ARGV to the sorted set KEYS[1] unless they are
+// already in it, scoring them with the counter KEYS[2] to keep their order.
+var addRules = rds.NewScript(`
+for _, rule in ipairs(ARGV) do
+	if not redis.call("ZSCORE", KEYS[1], rule) then
+		redis.call("ZADD", KEYS[1], redis.call("INCR", KEYS[2]), rule)
+	end
+end
+return 0
+`)
+
+// policyKey is the sorted set holding the policy rules stored for channel,
+// and policySeqKey the counter ordering them.
+func policyKey(channel string) string {
+	return hashTag(channel) + ":policy"
+}
+
+func policySeqKey(channel string) string {
+	return hashTag(channel) + ":policy:seq"
+}
+
+// Adapter is a persist.BatchAdapter storing the policy rules in Redis, next
+// to the keys of the watcher channel, so that small deployments get storage
+// and change notification from one component. Each rule is a member of a
+// sorted set, encoded as a JSON array of its ptype and values, and scored in
+// insertion order so that LoadPolicy returns the rules in the order they
+// were added.
+//
+// The adapter does not publish anything itself: the enforcer notifies the
+// watcher after saving, as with any adapter.
+type Adapter struct {
+	w *Watcher
+}
+
+var _ persist.BatchAdapter = (*Adapter)(nil)
+
+// Adapter returns an adapter storing the policy in the Redis of w, keyed by
+// the watcher channel.
+func (w *Watcher) Adapter() *Adapter {
+	return &Adapter{w: w}
+}
+
+func (a *Adapter) client() rds.UniversalClient {
+	_, client := a.w.clients()
+	return client
+}
+
+// LoadPolicy loads all policy rules from Redis. Rules of ptypes the model
+// does not define are skipped.
+func (a *Adapter) LoadPolicy(m model.Model) error {
+	members, err := a.client().ZRange(a.w.ctx, policyKey(a.w.options.Channel), 0, -1).Result()
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		var tokens []string
+		if err := json.Unmarshal([]byte(member), &tokens); err != nil {
+			return err
+		}
+		if len(tokens) < 2 || tokens[0] == "" {
+			continue
+		}
+		ast, ok := m[tokens[0][:1]][tokens[0]]
+		if !ok {
+			continue
+		}
+		ast.Policy = append(ast.Policy, tokens[1:])
+		ast.PolicyMap[strings.Join(tokens[1:], model.DefaultSep)] = len(ast.Policy) - 1
+	}
+	return nil
+}
+
+// SavePolicy replaces the rules stored in Redis by the "p" and "g" rules of
+// m, in one transaction.
+func (a *Adapter) SavePolicy(m model.Model) error {
+	var members []rds.Z
+	for _, sec := range []string{"p", "g"} {
+		ptypes := make([]string, 0, len(m[sec]))
+		for ptype := range m[sec] {
+			ptypes = append(ptypes, ptype)
+		}
+		sort.Strings(ptypes)
+		for _, ptype := range ptypes {
+			for _, rule := range m[sec][ptype].Policy {
+				member, err := encodeRule(ptype, rule)
+				if err != nil {
+					return err
+				}
+				members = append(members, rds.Z{Score: float64(len(members) + 1), Member: member})
+			}
+		}
+	}
+	channel := a.w.options.Channel
+	_, err := a.client().TxPipelined(a.w.ctx, func(pipe rds.Pipeliner) error {
+		pipe.Del(a.w.ctx, policyKey(channel))
+		if len(members) > 0 {
+			pipe.ZAdd(a.w.ctx, policyKey(channel), members...)
+		}
+		pipe.Set(a.w.ctx, policySeqKey(channel), len(members), 0)
+		return nil
+	})
+	return err
+}
+
+// AddPolicy adds a policy rule to Redis.
+func (a *Adapter) AddPolicy(sec string, ptype string, rule []string) error {
+	return a.AddPolicies(sec, ptype, [][]string{rule})
+}
+
+// RemovePolicy removes a policy rule from Redis.
+func (a *Adapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	return a.RemovePolicies(sec, ptype, [][]string{rule})
+}
+
+// AddPolicies adds policy rules to Redis, atomically.
+func (a *Adapter) AddPolicies(sec string, ptype string, rules [][]string) error {
+	members, err := encodeRules(ptype, rules)
+	if err != nil || len(members) == 0 {
+		return err
+	}
+	channel := a.w.options.Channel
+	keys := []string{policyKey(channel), policySeqKey(channel)}
+	return addRules.Run(a.w.ctx, a.client(), keys, members...).Err()
+}
+
+// RemovePolicies removes policy rules from Redis, atomically.
+func (a *Adapter) RemovePolicies(sec string, ptype string, rules [][]string) error {
+	members, err := encodeRules(ptype, rules)
+	if err != nil || len(members) == 0 {
+		return err
+	}
+	return a.client().ZRem(a.w.ctx, policyKey(a.w.options.Channel), members...).Err()
+}
+
+// RemoveFilteredPolicy removes the rules of ptype whose values starting at
+// fieldIndex match fieldValues, an empty value matching any. The rules are
+// read and then removed, so a rule added concurrently by another process
+// may be kept.
+func (a *Adapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	key := policyKey(a.w.options.Channel)
+	members, err := a.client().ZRange(a.w.ctx, key, 0, -1).Result()
+	if err != nil {
+		return err
+	}
+	var removed []interface{}
+	for _, member := range members {
+		var tokens []string
+		if err := json.Unmarshal([]byte(member), &tokens); err != nil {
+			return err
+		}
+		if len(tokens) > 0 && tokens[0] == ptype && matchesFilter(tokens[1:], fieldIndex, fieldValues) {
+			removed = append(removed, member)
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	return a.client().ZRem(a.w.ctx, key, removed...).Err()
+}
+
+// matchesFilter reports whether the values of rule starting at fieldIndex
+// match fieldValues, an empty value matching any.
+func matchesFilter(rule []string, fieldIndex int, fieldValues []string) bool {
+	for i, value := range fieldValues {
+		if value == "" {
+			continue
+		}
+		if fieldIndex+i >= len(rule) || rule[fieldIndex+i] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeRule encodes rule as a member of the policy sorted set.
+func encodeRule(ptype string, rule []string) (string, error) {
+	b, err := json.Marshal(append([]string{ptype}, rule...))
+	return string(b), err
+}
+
+func encodeRules(ptype string, rules [][]string) ([]interface{}, error) {
+	members := make([]interface{}, 0, len(rules))
+	for _, rule := range rules {
+		member, err := encodeRule(ptype, rule)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
@@ -0,0 +1,86 @@
+package rediswatcher
+
+import "errors"
+
+// Option changes an option of a running watcher, see ApplyOptions.
+type Option func(w *Watcher) error
+
+// ApplyOptions changes options of a running watcher, in order, and stops at
+// the first one that is invalid. It is safe to call concurrently with the
+// other methods. Other options can only be set when creating the watcher.
+func (w *Watcher) ApplyOptions(options ...Option) error {
+	for _, option := range options {
+		if err := option(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithIgnoreSelf sets WatcherOptions.IgnoreSelf.
+func WithIgnoreSelf(ignoreSelf bool) Option {
+	return func(w *Watcher) error {
+		w.dispatchL.Lock()
+		defer w.dispatchL.Unlock()
+		w.l.Lock()
+		defer w.l.Unlock()
+		w.options.IgnoreSelf = ignoreSelf
+		return nil
+	}
+}
+
+// WithChannel moves the watcher to channel, see SetChannel.
+func WithChannel(channel string) Option {
+	return func(w *Watcher) error {
+		return w.SetChannel(channel)
+	}
+}
+
+// WithLogger sets WatcherOptions.Logger.
+func WithLogger(logger Logger) Option {
+	return func(w *Watcher) error {
+		if logger == nil {
+			return errors.New("logger is nil")
+		}
+		w.l.Lock()
+		defer w.l.Unlock()
+		w.options.Logger = logger
+		w.setLogger(logger)
+		return nil
+	}
+}
+
+// WithPublishRate sets WatcherOptions.PublishRate and PublishBurst. A zero
+// rate removes the limit.
+func WithPublishRate(rate float64, burst int) Option {
+	return func(w *Watcher) error {
+		if rate < 0 || burst < 0 {
+			return errors.New("publish rate and burst must not be negative")
+		}
+		w.l.Lock()
+		defer w.l.Unlock()
+		w.options.PublishRate, w.options.PublishBurst = rate, burst
+		setRateDefaults(&w.options)
+		w.limiter = newTokenBucket(w.options)
+		return nil
+	}
+}
+
+// loggerBox holds the Logger in w.log, as an atomic.Value requires values of
+// a single type.
+type loggerBox struct {
+	Logger
+}
+
+// logger returns the Logger of the watcher, which WithLogger may replace
+// while it runs.
+func (w *Watcher) logger() Logger {
+	if box, ok := w.log.Load().(loggerBox); ok {
+		return box.Logger
+	}
+	return w.options.Logger
+}
+
+func (w *Watcher) setLogger(logger Logger) {
+	w.log.Store(loggerBox{logger})
+}
@@ -0,0 +1,97 @@
+package rediswatcher
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Overflow policies supported by WatcherOptions.QueueOverflow.
+const (
+	// OverflowBlock makes the Update methods wait for room in the queue.
+	OverflowBlock = "block"
+	// OverflowDrop drops the message and logs a warning.
+	OverflowDrop = "drop"
+	// OverflowError fails the Update methods with ErrQueueFull.
+	OverflowError = "error"
+)
+
+// defaultPublishQueueSize is the PublishQueueSize used when it is not set.
+const defaultPublishQueueSize = 1000
+
+// ErrQueueFull is returned by the Update methods when the publish queue is
+// full and QueueOverflow is OverflowError.
+var ErrQueueFull = errors.New("publish queue is full")
+
+// validateAsync checks the asynchronous publishing settings and fills in
+// defaults.
+func validateAsync(option *WatcherOptions) error {
+	if !option.AsyncPublish {
+		return nil
+	}
+	if option.PublishQueueSize <= 0 {
+		option.PublishQueueSize = defaultPublishQueueSize
+	}
+	switch option.QueueOverflow {
+	case "":
+		option.QueueOverflow = OverflowBlock
+	case OverflowBlock, OverflowDrop, OverflowError:
+	default:
+		return fmt.Errorf("unsupported queue overflow policy %q", option.QueueOverflow)
+	}
+	return nil
+}
+
+// startQueue starts the background publisher when AsyncPublish is set.
+func (w *Watcher) startQueue() {
+	if !w.options.AsyncPublish {
+		return
+	}
+	w.queue = make(chan MSG, w.options.PublishQueueSize)
+	w.queueDone = make(chan struct{})
+	go w.flush()
+}
+
+// flush publishes the queued messages until the queue is closed.
+func (w *Watcher) flush() {
+	defer close(w.queueDone)
+	for m := range w.queue {
+		if err := w.publishNow(m); err != nil {
+			w.reportError(err)
+		}
+	}
+}
+
+// enqueue queues m for the background publisher according to
+// QueueOverflow. It must be called with w.l held.
+func (w *Watcher) enqueue(m MSG) error {
+	if w.closed() {
+		return ErrClosed
+	}
+	switch w.options.QueueOverflow {
+	case OverflowDrop:
+		select {
+		case w.queue <- m:
+		default:
+			w.logger().Warn(fmt.Sprintf("dropping %s message: %v", m.Method, ErrQueueFull))
+		}
+	case OverflowError:
+		select {
+		case w.queue <- m:
+		default:
+			return ErrQueueFull
+		}
+	default:
+		w.queue <- m
+	}
+	return nil
+}
+
+// drainQueue publishes the messages still queued and stops the background
+// publisher. It must be called with w.l held.
+func (w *Watcher) drainQueue() {
+	if w.queue == nil {
+		return
+	}
+	close(w.queue)
+	<-w.queueDone
+}
@@ -0,0 +1,99 @@
+package rediswatcher
+
+import (
+	"sync"
+
+	rds "github.com/redis/go-redis/v9"
+)
+
+// dedupWindow is the number of recent message IDs remembered to drop copies
+// of a message received through more than one backend.
+const dedupWindow = 1024
+
+// dedup remembers the most recent message IDs in a fixed size ring.
+type dedup struct {
+	l    sync.Mutex
+	seen map[string]struct{}
+	ring []string
+	next int
+}
+
+func newDedup(size int) *dedup {
+	return &dedup{
+		seen: make(map[string]struct{}, size),
+		ring: make([]string, size),
+	}
+}
+
+// seenBefore records id and reports whether it was already recorded.
+func (d *dedup) seenBefore(id string) bool {
+	d.l.Lock()
+	defer d.l.Unlock()
+	if _, ok := d.seen[id]; ok {
+		return true
+	}
+	if old := d.ring[d.next]; old != "" {
+		delete(d.seen, old)
+	}
+	d.ring[d.next] = id
+	d.next = (d.next + 1) % len(d.ring)
+	d.seen[id] = struct{}{}
+	return false
+}
+
+// newBackends creates a client for every redundant backend in option.
+func newBackends(option WatcherOptions) []*rds.Client {
+	clients := make([]*rds.Client, 0, len(option.Backends))
+	for i := range option.Backends {
+		clients = append(clients, rds.NewClient(&option.Backends[i]))
+	}
+	return clients
+}
+
+// publishPayload publishes payload on channel of the main client and fans it out to the
+// redundant backends. With backends configured delivery is best effort: the
+// call succeeds when at least one Redis accepted the message.
+func (w *Watcher) publishPayload(channel string, payload interface{}) error {
+	return w.publishBackends(channel, payload, w.send(w.pubClient, channel, payload))
+}
+
+// publishBackends sends payload on channel of the redundant backends once
+// err was returned sending it with the main client, see publishPayload.
+func (w *Watcher) publishBackends(channel string, payload interface{}, err error) error {
+	if len(w.backends) == 0 {
+		return err
+	}
+	delivered := err == nil
+	if err != nil {
+		w.logger().Warn(err)
+	}
+	for _, client := range w.backends {
+		if e := w.send(client, channel, payload); e != nil {
+			w.logger().Warn(e)
+			continue
+		}
+		delivered = true
+	}
+	if delivered {
+		return nil
+	}
+	return err
+}
+
+// subscribeBackends subscribes to the watcher channel on every redundant
+// backend. A backend that is down is retried in the background and does not
+// prevent the watcher from starting.
+func (w *Watcher) subscribeBackends() {
+	for _, client := range w.backends {
+		sub, err := w.openSubscription(client)
+		w.l.Lock()
+		s := w.track(sub)
+		w.l.Unlock()
+		if err != nil {
+			w.reportError(err)
+			s.fail(err)
+		}
+		client, s := client, s
+		w.start(func() { w.listen(client, s) })
+	}
+}
@@ -0,0 +1,216 @@
+package rediswatcher
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	rds "github.com/redis/go-redis/v9"
+)
+
+// seqKey is the counter numbering the messages published on channel. The
+// keys are hash tagged with the channel so a cluster keeps them on one node.
+func seqKey(channel string) string {
+	return hashTag(channel) + ":seq"
+}
+
+// backlogKey is the sorted set holding the last messages published on
+// channel, scored by sequence number.
+func backlogKey(channel string) string {
+	return hashTag(channel) + ":backlog"
+}
+
+// validateBacklog checks that the options can be served with a backlog.
+func validateBacklog(option *WatcherOptions) error {
+	if option.BacklogSize <= 0 {
+		return nil
+	}
+	if option.Transport == TransportStream {
+		return errors.New("a backlog is not supported with the stream transport, which replays the stream instead")
+	}
+	if len(option.Backends) > 0 {
+		return errors.New("a backlog is not supported with redundant backends")
+	}
+	if len(option.Channels) > 0 || option.ChannelPattern != "" {
+		return errors.New("a backlog supports a single channel only")
+	}
+	return nil
+}
+
+// initSequence starts delivery after the last message published so far.
+func (w *Watcher) initSequence() error {
+	seq, err := w.subClient.Get(w.ctx, seqKey(w.options.Channel)).Int64()
+	if err != nil && err != rds.Nil {
+		return err
+	}
+	w.lastSeq = seq
+	return nil
+}
+
+// publishNumberedScript publishes the message ARGV[1] by appending it to
+// the command ARGV[6..], provided it follows the counters it is numbered
+// with, which it then advances: numbered ARGV[2], it is stored in the
+// backlog KEYS[2] capped at ARGV[3] entries and becomes the sequence
+// KEYS[1], and of epoch ARGV[4] it becomes the epoch KEYS[3]. An empty
+// number is not checked. With ARGV[5] set the poll version KEYS[4] is
+// bumped as well. The channel is part of the command rather than of KEYS,
+// as it is not hash tagged like the keys with DisableHashTag. It returns
+// {1, version} on success and {0, sequence, epoch} otherwise, so that the
+// caller renumbers the message and tries again.
+var publishNumberedScript = rds.NewScript(`
+local seq, epoch = tonumber(ARGV[2]), tonumber(ARGV[4])
+local currentSeq = tonumber(redis.call("GET", KEYS[1]) or "0")
+local currentEpoch = tonumber(redis.call("GET", KEYS[3]) or "0")
+if (seq and currentSeq + 1 ~= seq) or (epoch and currentEpoch + 1 ~= epoch) then
+	return {0, currentSeq, currentEpoch}
+end
+if seq then
+	redis.call("SET", KEYS[1], seq)
+	redis.call("ZADD", KEYS[2], seq, ARGV[1])
+	redis.call("ZREMRANGEBYRANK", KEYS[2], 0, -tonumber(ARGV[3]) - 1)
+end
+if epoch then
+	redis.call("SET", KEYS[3], epoch)
+end
+local command = {}
+for i = 6, #ARGV do
+	command[#command + 1] = ARGV[i]
+end
+command[#command + 1] = ARGV[1]
+redis.call(unpack(command))
+local version = 0
+if ARGV[5] ~= "" then
+	version = redis.call("INCR", KEYS[4])
+end
+return {1, version}
+`)
+
+// maxSequenceAttempts bounds how often publishNumbered renumbers a message
+// raced by other publishers.
+const maxSequenceAttempts = 100
+
+// numbered reports whether m, published on channel, is numbered with the
+// backlog sequence or the policy epoch, or bumps the poll version.
+func (w *Watcher) numbered(channel string, m *MSG) bool {
+	return w.options.BacklogSize > 0 || w.options.PolicyEpoch && m.Method != MethodPolicyHash || w.versioned(channel, m)
+}
+
+// publishNumbered numbers m, stores it in the backlog, publishes it on
+// channel and bumps the poll version in one script, so that the counters,
+// the backlog and the messages published cannot diverge: a publisher
+// failing midway leaves no gap and concurrent publishers publish the
+// messages in the order of their numbers, which subscribers rely on to
+// deliver them in sequence and to skip the stale epochs. The redundant
+// backends then get the message as is.
+func (w *Watcher) publishNumbered(channel string, m *MSG) error {
+	sequenced := w.options.BacklogSize > 0
+	epoched := w.options.PolicyEpoch && m.Method != MethodPolicyHash
+	versioned := w.versioned(channel, m)
+	keys := []string{seqKey(channel), backlogKey(channel), epochKey(channel), versionKey(channel)}
+	command, trim := w.sendCommand(channel)
+	counters, err := w.pubClient.MGet(w.ctx, keys[0], keys[2]).Result()
+	if err != nil {
+		return err
+	}
+	seq, epoch := counterValue(counters[0]), counterValue(counters[1])
+	for i := 0; i < maxSequenceAttempts; i++ {
+		args := []interface{}{nil, "", w.options.BacklogSize, "", ""}
+		if sequenced {
+			m.Seq = seq + 1
+			args[1] = m.Seq
+		}
+		if epoched {
+			m.Epoch = epoch + 1
+			args[3] = m.Epoch
+		}
+		if versioned {
+			args[4] = 1
+		}
+		data, err := w.encode(m)
+		if err != nil {
+			return err
+		}
+		args[0] = data
+		result, err := publishNumberedScript.Run(w.ctx, w.pubClient, keys, append(args, command...)...).Int64Slice()
+		if err != nil {
+			return err
+		}
+		if result[0] == 1 {
+			if versioned {
+				atomic.StoreInt64(&w.ownVersion, result[1])
+			}
+			if trim != nil {
+				trim()
+			}
+			return w.publishBackends(channel, data, nil)
+		}
+		seq, epoch = result[1], result[2]
+	}
+	return fmt.Errorf("could not number the message after %d attempts", maxSequenceAttempts)
+}
+
+// counterValue returns the counter read with MGET, 0 when not set.
+func counterValue(v interface{}) int64 {
+	s, _ := v.(string)
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
+
+// inSequence reports whether the message numbered seq should be delivered.
+// Messages that were already delivered are dropped, and when seq reveals a
+// gap the missed messages are replayed from the backlog first. It must be
+// called with w.dispatchL held.
+func (w *Watcher) inSequence(channel string, seq int64) bool {
+	if seq <= w.lastSeq {
+		return false
+	}
+	if seq > w.lastSeq+1 {
+		w.replay(channel, seq-1)
+	}
+	w.lastSeq = seq
+	return true
+}
+
+// replay delivers the backlog messages numbered up to upTo that were not
+// delivered yet. It must be called with w.dispatchL held.
+func (w *Watcher) replay(channel string, upTo int64) {
+	entries, err := w.subClient.ZRangeByScoreWithScores(w.ctx, backlogKey(w.options.Channel), &rds.ZRangeBy{
+		Min: "(" + strconv.FormatInt(w.lastSeq, 10),
+		Max: strconv.FormatInt(upTo, 10),
+	}).Result()
+	if err != nil {
+		w.reportError(err)
+		return
+	}
+	for _, entry := range entries {
+		seq := int64(entry.Score)
+		if seq > w.lastSeq+1 {
+			w.logger().Warn(fmt.Sprintf("messages %d to %d are no longer in the backlog", w.lastSeq+1, seq-1))
+		}
+		w.lastSeq = seq - 1
+		if data, ok := entry.Member.(string); ok {
+			w.deliver(channel, data)
+		}
+	}
+	if w.lastSeq < upTo {
+		w.logger().Warn(fmt.Sprintf("messages %d to %d are no longer in the backlog", w.lastSeq+1, upTo))
+	}
+}
+
+// catchUp replays the messages published while the subscription was down.
+func (w *Watcher) catchUp() {
+	seq, err := w.subClient.Get(w.ctx, seqKey(w.options.Channel)).Int64()
+	if err != nil {
+		if err != rds.Nil {
+			w.reportError(err)
+		}
+		return
+	}
+	w.dispatchL.Lock()
+	defer w.dispatchL.Unlock()
+	if seq > w.lastSeq {
+		w.replay(w.options.Channel, seq)
+		w.lastSeq = seq
+	}
+}
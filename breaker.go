@@ -0,0 +1,85 @@
+package rediswatcher
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of publishing while the circuit breaker
+// is open, see WatcherOptions.BreakerThreshold.
+var ErrCircuitOpen = errors.New("circuit breaker is open: redis is failing")
+
+// defaultBreakerCooldown is the BreakerCooldown used when it is not set.
+const defaultBreakerCooldown = 5 * time.Second
+
+// breaker fails publishes fast after threshold consecutive failures. Once
+// cooldown elapsed a single publish is let through to probe Redis: success
+// closes the breaker, failure keeps it open for another cooldown.
+type breaker struct {
+	l         sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	probing   bool
+}
+
+func newBreaker(option WatcherOptions) *breaker {
+	if option.BreakerThreshold <= 0 {
+		return nil
+	}
+	return &breaker{threshold: option.BreakerThreshold, cooldown: option.BreakerCooldown}
+}
+
+// allow reports whether a publish may be attempted.
+func (b *breaker) allow() bool {
+	b.l.Lock()
+	defer b.l.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.probing || time.Since(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record records the outcome of an allowed publish and returns whether it
+// opened or closed the breaker.
+func (b *breaker) record(err error) (opened, closed bool) {
+	b.l.Lock()
+	defer b.l.Unlock()
+	wasOpen := b.failures >= b.threshold
+	b.probing = false
+	if err == nil {
+		b.failures = 0
+		return false, wasOpen
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = time.Now()
+		return !wasOpen, false
+	}
+	return false, false
+}
+
+// publishGuarded publishes m through the circuit breaker, if any.
+func (w *Watcher) publishGuarded(m MSG) error {
+	if w.breaker == nil {
+		return w.publishMSG(m)
+	}
+	if !w.breaker.allow() {
+		return ErrCircuitOpen
+	}
+	err := w.publishMSG(m)
+	switch opened, closed := w.breaker.record(err); {
+	case opened:
+		w.logger().Warn(fmt.Sprintf("circuit breaker opened after %d consecutive publish failures: %v", w.options.BreakerThreshold, err))
+	case closed:
+		w.logger().Info("circuit breaker closed")
+	}
+	return err
+}
@@ -0,0 +1,102 @@
+package rediswatcher
+
+import (
+	"errors"
+
+	rds "github.com/redis/go-redis/v9"
+)
+
+// validateChannels normalizes the additional channels and the channel
+// pattern the same way as the main channel.
+func validateChannels(option *WatcherOptions) error {
+	if (len(option.Channels) > 0 || option.ChannelPattern != "") && option.Transport == TransportStream {
+		return errors.New("additional channels are not supported with the stream transport")
+	}
+	channels := make([]string, 0, len(option.Channels))
+	for _, channel := range option.Channels {
+		normalized, err := normalizeChannel(channel)
+		if err != nil {
+			return err
+		}
+		channels = append(channels, normalized)
+	}
+	option.Channels = channels
+	if option.ChannelPattern != "" {
+		pattern, err := normalizeChannel(option.ChannelPattern)
+		if err != nil {
+			return err
+		}
+		option.ChannelPattern = pattern
+	}
+	return nil
+}
+
+// openSubscription subscribes client to the main channel, the additional
+// channels, the tenant channels, the channel pattern and keyspace notifications, and waits until
+// Redis confirmed every subscription so that messages published right after
+// NewWatcher returns are not missed. The subscription is returned even when
+// an error occurred so the caller can close it.
+func (w *Watcher) openSubscription(client rds.UniversalClient) (*rds.PubSub, error) {
+	channels := append([]string{w.options.Channel}, w.options.Channels...)
+	channels = append(channels, w.tenantChannels()...)
+	var sub *rds.PubSub
+	if w.options.ShardedPubSub {
+		sub = client.SSubscribe(w.ctx, channels...)
+	} else {
+		sub = client.Subscribe(w.ctx, channels...)
+	}
+	if err := w.confirm(sub, len(channels)); err != nil {
+		return sub, err
+	}
+	if w.options.ChannelPattern != "" {
+		if err := sub.PSubscribe(w.ctx, w.options.ChannelPattern); err != nil {
+			return sub, err
+		}
+		if err := w.confirm(sub, 1); err != nil {
+			return sub, err
+		}
+	}
+	return sub, w.subscribeKeyspace(sub)
+}
+
+// confirm reads from sub until Redis confirmed n more subscriptions. The
+// messages received meanwhile on the channels already subscribed are
+// dispatched rather than dropped.
+func (w *Watcher) confirm(sub *rds.PubSub, n int) error {
+	for n > 0 {
+		msg, err := sub.Receive(w.ctx)
+		if err != nil {
+			return err
+		}
+		switch m := msg.(type) {
+		case *rds.Subscription:
+			if m.Kind == "subscribe" || m.Kind == "ssubscribe" || m.Kind == "psubscribe" {
+				n--
+			}
+		case *rds.Message:
+			w.receive(m)
+		}
+	}
+	return nil
+}
+
+// receive dispatches a message received on a subscription opened by
+// openSubscription.
+func (w *Watcher) receive(msg *rds.Message) {
+	if w.options.KeyspacePattern != "" && msg.Pattern == keyspacePattern(&w.options) {
+		w.dispatchKeyspaceEvent(msg)
+		return
+	}
+	w.dispatch(msg.Channel, msg.Payload)
+}
+
+// SetUpdateCallbackWithChannel sets a callback that, unlike the one set by
+// SetUpdateCallback, also receives the channel a message arrived on. It is
+// useful when a watcher listens on several channels or a channel pattern,
+// e.g. one per tenant. When set it replaces the update callback.
+func (w *Watcher) SetUpdateCallbackWithChannel(callback func(channel, msg string)) error {
+	w.l.Lock()
+	w.channelCallback = callback
+	w.l.Unlock()
+	return nil
+}
@@ -0,0 +1,95 @@
+package rediswatcher
+
+import (
+	rds "github.com/redis/go-redis/v9"
+)
+
+// newClient builds a client for the topology selected in option: a cluster
+// client when ClusterAddrs is set, a sentinel failover client when MasterName
+// is set and a single node client for Options.Addr otherwise. Connection
+// settings such as Username, Password, TLSConfig, Dialer, OnConnect, the
+// timeouts and the retries, and the pool settings such as PoolSize and
+// ConnMaxIdleTime, are taken from the embedded Options for every topology,
+// so a TLS enabled deployment only configures them once.
+func newClient(option *WatcherOptions) rds.UniversalClient {
+	switch {
+	case len(option.ClusterAddrs) > 0:
+		return rds.NewClusterClient(clusterOptions(option))
+	case option.MasterName != "":
+		return rds.NewFailoverClient(failoverOptions(option))
+	default:
+		return rds.NewClient(&option.Options)
+	}
+}
+
+func clusterOptions(option *WatcherOptions) *rds.ClusterOptions {
+	o := &option.Options
+	return &rds.ClusterOptions{
+		Addrs:          option.ClusterAddrs,
+		Username:       o.Username,
+		Password:       o.Password,
+		TLSConfig:      o.TLSConfig,
+		ClientName:     o.ClientName,
+		OnConnect:      o.OnConnect,
+		Dialer:         o.Dialer,
+		ReadOnly:       option.ClusterReadOnly,
+		RouteByLatency: option.ClusterRouteByLatency,
+		RouteRandomly:  option.ClusterRouteRandomly,
+
+		CredentialsProvider:        o.CredentialsProvider,
+		CredentialsProviderContext: o.CredentialsProviderContext,
+
+		DialTimeout:           o.DialTimeout,
+		ReadTimeout:           o.ReadTimeout,
+		WriteTimeout:          o.WriteTimeout,
+		ContextTimeoutEnabled: o.ContextTimeoutEnabled,
+
+		MaxRetries:      o.MaxRetries,
+		MinRetryBackoff: o.MinRetryBackoff,
+		MaxRetryBackoff: o.MaxRetryBackoff,
+
+		PoolFIFO:        o.PoolFIFO,
+		PoolSize:        o.PoolSize,
+		PoolTimeout:     o.PoolTimeout,
+		MinIdleConns:    o.MinIdleConns,
+		MaxIdleConns:    o.MaxIdleConns,
+		MaxActiveConns:  o.MaxActiveConns,
+		ConnMaxIdleTime: o.ConnMaxIdleTime,
+		ConnMaxLifetime: o.ConnMaxLifetime,
+	}
+}
+
+func failoverOptions(option *WatcherOptions) *rds.FailoverOptions {
+	o := &option.Options
+	return &rds.FailoverOptions{
+		MasterName:       option.MasterName,
+		SentinelAddrs:    option.SentinelAddrs,
+		SentinelUsername: option.SentinelUsername,
+		SentinelPassword: option.SentinelPassword,
+		Username:         o.Username,
+		Password:         o.Password,
+		DB:               o.DB,
+		TLSConfig:        o.TLSConfig,
+		ClientName:       o.ClientName,
+		OnConnect:        o.OnConnect,
+		Dialer:           o.Dialer,
+
+		DialTimeout:           o.DialTimeout,
+		ReadTimeout:           o.ReadTimeout,
+		WriteTimeout:          o.WriteTimeout,
+		ContextTimeoutEnabled: o.ContextTimeoutEnabled,
+
+		MaxRetries:      o.MaxRetries,
+		MinRetryBackoff: o.MinRetryBackoff,
+		MaxRetryBackoff: o.MaxRetryBackoff,
+
+		PoolFIFO:        o.PoolFIFO,
+		PoolSize:        o.PoolSize,
+		PoolTimeout:     o.PoolTimeout,
+		MinIdleConns:    o.MinIdleConns,
+		MaxIdleConns:    o.MaxIdleConns,
+		MaxActiveConns:  o.MaxActiveConns,
+		ConnMaxIdleTime: o.ConnMaxIdleTime,
+		ConnMaxLifetime: o.ConnMaxLifetime,
+	}
+}
@@ -0,0 +1,196 @@
+package rediswatcher
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// binaryModelMarker starts the messages encoded by BinaryModelSerializer
+// with a binary model. It cannot start a JSON encoding.
+const binaryModelMarker = 0x01
+
+// BinaryModelSerializer encodes the model of UpdateForSavePolicy messages in
+// a compact binary form, which is smaller and faster to encode and decode
+// than JSON for large policies, and every other message as JSON. It decodes
+// JSON messages as well, so it can be rolled out before the publishers
+// switch to it. The binary form carries the key, value, tokens and policy
+// of every assertion, role managers are not transmitted.
+type BinaryModelSerializer struct{}
+
+// Marshal encodes an UpdateForSavePolicy message as the marker, the length
+// of the JSON encoding of the message without its model, that encoding and
+// the binary model.
+func (BinaryModelSerializer) Marshal(m *MSG) ([]byte, error) {
+	if m.Method != MethodUpdateForSavePolicy || m.Params == nil {
+		return m.MarshalBinary()
+	}
+	params, err := m.ModelParams()
+	if err != nil {
+		return m.MarshalBinary()
+	}
+	header := *m
+	header.Params = nil
+	data, err := json.Marshal(&header)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 0, len(data)+64)
+	b = append(b, binaryModelMarker)
+	b = appendUvarint(b, uint64(len(data)))
+	b = append(b, data...)
+	return appendModel(b, params), nil
+}
+
+func (BinaryModelSerializer) Unmarshal(data []byte, m *MSG) error {
+	if len(data) == 0 || data[0] != binaryModelMarker {
+		return m.UnmarshalBinary(data)
+	}
+	r := &modelReader{data: data[1:]}
+	header := r.bytes()
+	if r.err != nil {
+		return r.err
+	}
+	if err := m.UnmarshalBinary(header); err != nil {
+		return err
+	}
+	params := r.model()
+	if r.err != nil {
+		return r.err
+	}
+	m.Params = params
+	return nil
+}
+
+// appendModel appends the binary encoding of m to b: its sections, and
+// within them its assertions, in sorted order, each string prefixed by its
+// length and each list by its number of items.
+func appendModel(b []byte, m model.Model) []byte {
+	secs := make([]string, 0, len(m))
+	for sec := range m {
+		secs = append(secs, sec)
+	}
+	sort.Strings(secs)
+	b = appendUvarint(b, uint64(len(secs)))
+	for _, sec := range secs {
+		ptypes := make([]string, 0, len(m[sec]))
+		for ptype := range m[sec] {
+			ptypes = append(ptypes, ptype)
+		}
+		sort.Strings(ptypes)
+		b = appendString(b, sec)
+		b = appendUvarint(b, uint64(len(ptypes)))
+		for _, ptype := range ptypes {
+			a := m[sec][ptype]
+			b = appendString(b, ptype)
+			b = appendString(b, a.Key)
+			b = appendString(b, a.Value)
+			b = appendStrings(b, a.Tokens)
+			b = appendUvarint(b, uint64(len(a.Policy)))
+			for _, rule := range a.Policy {
+				b = appendStrings(b, rule)
+			}
+		}
+	}
+	return b
+}
+
+// appendUvarint appends the varint encoding of n to b, like
+// binary.AppendUvarint, which was added in Go 1.19 while the module still
+// supports Go 1.18.
+func appendUvarint(b []byte, n uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], n)]...)
+}
+
+func appendString(b []byte, s string) []byte {
+	b = appendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendStrings(b []byte, values []string) []byte {
+	b = appendUvarint(b, uint64(len(values)))
+	for _, s := range values {
+		b = appendString(b, s)
+	}
+	return b
+}
+
+var errTruncatedModel = errors.New("truncated binary model")
+
+// modelReader decodes the encoding of appendModel. The first error is kept
+// in err and stops the decoding.
+type modelReader struct {
+	data []byte
+	err  error
+}
+
+// count reads a length or number of items, which cannot exceed the number
+// of bytes left as every item takes at least one.
+func (r *modelReader) count() int {
+	if r.err != nil {
+		return 0
+	}
+	n, size := binary.Uvarint(r.data)
+	if size <= 0 || n > uint64(len(r.data)-size) {
+		r.err = errTruncatedModel
+		return 0
+	}
+	r.data = r.data[size:]
+	return int(n)
+}
+
+func (r *modelReader) bytes() []byte {
+	n := r.count()
+	if r.err != nil {
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *modelReader) string() string {
+	return string(r.bytes())
+}
+
+func (r *modelReader) strings() []string {
+	n := r.count()
+	if n == 0 {
+		return nil
+	}
+	values := make([]string, 0, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		values = append(values, r.string())
+	}
+	return values
+}
+
+func (r *modelReader) model() model.Model {
+	m := model.Model{}
+	for secs := r.count(); secs > 0 && r.err == nil; secs-- {
+		sec := r.string()
+		m[sec] = model.AssertionMap{}
+		for ptypes := r.count(); ptypes > 0 && r.err == nil; ptypes-- {
+			ptype := r.string()
+			a := &model.Assertion{Key: r.string(), Value: r.string(), Tokens: r.strings()}
+			rules := r.count()
+			a.Policy = make([][]string, 0, rules)
+			a.PolicyMap = make(map[string]int, rules)
+			for i := 0; i < rules && r.err == nil; i++ {
+				rule := r.strings()
+				a.PolicyMap[strings.Join(rule, model.DefaultSep)] = i
+				a.Policy = append(a.Policy, rule)
+			}
+			m[sec][ptype] = a
+		}
+	}
+	if r.err == nil && len(r.data) > 0 {
+		r.err = errors.New("trailing data after binary model")
+	}
+	return m
+}
@@ -0,0 +1,71 @@
+package rediswatcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// defaultCompressionThreshold is the encoded size above which messages are
+// compressed when CompressionThreshold is not set.
+const defaultCompressionThreshold = 1024
+
+// Compressor compresses large messages, see WatcherOptions.Compressor. Name
+// identifies the algorithm on the wire and must be unique.
+type Compressor interface {
+	Name() string
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// GzipCompressor compresses messages with gzip. Receivers always decompress
+// gzip, whatever their own Compressor. Level is a compress/gzip level, zero
+// selects gzip.DefaultCompression.
+type GzipCompressor struct {
+	Level int
+}
+
+func (GzipCompressor) Name() string {
+	return "gzip"
+}
+
+func (c GzipCompressor) Compress(data []byte) ([]byte, error) {
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GzipCompressor) Decompress(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// compressor returns the Compressor for the algorithm named encoding.
+func (w *Watcher) compressor(encoding string) (Compressor, error) {
+	switch {
+	case w.options.Compressor != nil && encoding == w.options.Compressor.Name():
+		return w.options.Compressor, nil
+	case encoding == (GzipCompressor{}).Name():
+		return GzipCompressor{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported message encoding %q", encoding)
+	}
+}
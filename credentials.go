@@ -0,0 +1,140 @@
+package rediswatcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// validateCredentials checks the credentials provider settings and lets
+// the pooled connections expire with the credentials.
+func validateCredentials(option *WatcherOptions) error {
+	provided := option.CredentialsProvider != nil || option.CredentialsProviderContext != nil
+	if provided && option.MasterName != "" {
+		return errors.New("credentials providers are not supported with Sentinel")
+	}
+	if option.CredentialsRefreshInterval < 0 {
+		return errors.New("CredentialsRefreshInterval must not be negative")
+	}
+	if option.CredentialsRefreshInterval == 0 {
+		return nil
+	}
+	if option.CredentialsProviderContext == nil {
+		return errors.New("CredentialsRefreshInterval requires a CredentialsProviderContext")
+	}
+	if option.ConnMaxLifetime == 0 {
+		option.ConnMaxLifetime = option.CredentialsRefreshInterval
+	}
+	return nil
+}
+
+// credentials caches the credentials returned by a provider, so that new
+// connections do not call it, e.g. to request a token, every time.
+type credentials struct {
+	provider func(ctx context.Context) (string, string, error)
+
+	l                  sync.Mutex
+	username, password string
+	fetched            bool
+}
+
+// newCredentials replaces the CredentialsProviderContext of option, when
+// it is refreshed, by the cache returned.
+func newCredentials(option *WatcherOptions) *credentials {
+	if option.CredentialsRefreshInterval <= 0 {
+		return nil
+	}
+	c := &credentials{provider: option.CredentialsProviderContext}
+	option.CredentialsProviderContext = c.get
+	return c
+}
+
+// get returns the cached credentials, calling the provider the first time.
+func (c *credentials) get(ctx context.Context) (string, string, error) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	if !c.fetched {
+		if err := c.fetch(ctx); err != nil {
+			return "", "", err
+		}
+	}
+	return c.username, c.password, nil
+}
+
+// refresh calls the provider and reports whether the credentials changed.
+func (c *credentials) refresh(ctx context.Context) (bool, error) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	username, password := c.username, c.password
+	if err := c.fetch(ctx); err != nil {
+		return false, err
+	}
+	return c.username != username || c.password != password, nil
+}
+
+// fetch must be called with c.l held.
+func (c *credentials) fetch(ctx context.Context) error {
+	username, password, err := c.provider(ctx)
+	if err != nil {
+		return err
+	}
+	c.username, c.password, c.fetched = username, password, true
+	return nil
+}
+
+// startCredentialsRefresh refreshes the credentials every
+// CredentialsRefreshInterval. When they changed the subscriptions are
+// re-created, authenticating with the new credentials, while the pooled
+// connections expire after ConnMaxLifetime.
+func (w *Watcher) startCredentialsRefresh() {
+	if w.credentials == nil {
+		return
+	}
+	closed, ctx := w.close, w.ctx
+	w.start(func() {
+		ticker := time.NewTicker(w.options.CredentialsRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-closed:
+				return
+			case <-ticker.C:
+			}
+			changed, err := w.credentials.refresh(ctx)
+			if err != nil {
+				if !w.closed() {
+					w.reportError(err)
+				}
+				continue
+			}
+			if !changed {
+				continue
+			}
+			w.logger().Info("credentials refreshed, resubscribing")
+			w.l.Lock()
+			subs := w.subs
+			w.l.Unlock()
+			for _, s := range subs {
+				s.reset()
+			}
+		}
+	})
+}
+
+// RotateCredentials reconnects the watcher with new credentials, e.g. when
+// the application learns that the password was rotated, without
+// restarting it. The credentials are fetched again from the
+// CredentialsProviderContext of Options, which is otherwise called for
+// every new connection, or only every CredentialsRefreshInterval when set,
+// and the clients are re-created like Restart does. Clients passed in
+// SubClient and PubClient are reused. Like Restart it must not be called
+// from a callback.
+func (w *Watcher) RotateCredentials(ctx context.Context) error {
+	if w.credentials != nil {
+		if _, err := w.credentials.refresh(ctx); err != nil {
+			return err
+		}
+	}
+	return w.restart(ctx, true, nil)
+}
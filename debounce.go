@@ -0,0 +1,49 @@
+package rediswatcher
+
+import "time"
+
+// debounce holds m back for DebounceWindow, so that a burst of updates is
+// coalesced into a single message. It must be called with w.l held.
+func (w *Watcher) debounce(m MSG) error {
+	if w.closed() {
+		return ErrClosed
+	}
+	w.pending = append(w.pending, m)
+	if w.debounceTimer == nil {
+		w.debounceTimer = time.AfterFunc(w.options.DebounceWindow, func() {
+			w.l.Lock()
+			defer w.l.Unlock()
+			if err := w.flushPending(); err != nil {
+				w.reportError(err)
+			}
+		})
+	}
+	return nil
+}
+
+// flushPending publishes the messages held back by debounce: a single
+// message as is, several ones as one Update, which makes the receivers
+// reload the whole policy. It must be called with w.l held.
+func (w *Watcher) flushPending() error {
+	if w.debounceTimer != nil {
+		w.debounceTimer.Stop()
+		w.debounceTimer = nil
+	}
+	pending := w.pending
+	w.pending = nil
+	switch len(pending) {
+	case 0:
+		return nil
+	case 1:
+		return w.emit(pending[0])
+	default:
+		return w.emit(MSG{Method: MethodUpdate})
+	}
+}
+
+// coalescable reports whether m may be held back by debounce. Messages that
+// are awaited by the publisher, handled internally, published on a tenant
+// channel or carrying metadata are sent right away.
+func coalescable(m MSG) bool {
+	return m.ReplyTo == "" && m.Method != MethodPolicyHash && m.channel == "" && m.Metadata == nil
+}
@@ -0,0 +1,160 @@
+package rediswatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoAddrs is returned when the discovery of the Redis addresses returns
+// none, see WatcherOptions.Discover.
+var ErrNoAddrs = errors.New("discovery returned no Redis addresses")
+
+// SRVDiscovery returns a WatcherOptions.Discover function looking up the
+// DNS SRV record name, e.g. "_redis._tcp.redis.default.svc.cluster.local",
+// and returning the "host:port" of its targets in the order of priority.
+func SRVDiscovery(name string) func(ctx context.Context) ([]string, error) {
+	return func(ctx context.Context) ([]string, error) {
+		_, records, err := net.DefaultResolver.LookupSRV(ctx, "", "", name)
+		if err != nil {
+			return nil, err
+		}
+		addrs := make([]string, 0, len(records))
+		for _, record := range records {
+			host := strings.TrimSuffix(record.Target, ".")
+			addrs = append(addrs, net.JoinHostPort(host, strconv.Itoa(int(record.Port))))
+		}
+		return addrs, nil
+	}
+}
+
+// discoverAddrs sets the addresses of option from Discover, if set.
+func discoverAddrs(option *WatcherOptions) error {
+	if option.Discover == nil {
+		return nil
+	}
+	if option.DiscoveryInterval < 0 {
+		return fmt.Errorf("invalid DiscoveryInterval %v", option.DiscoveryInterval)
+	}
+	addrs, err := lookupAddrs(context.Background(), option)
+	if err != nil {
+		return err
+	}
+	setAddrs(option, addrs)
+	return nil
+}
+
+// lookupAddrs calls Discover, bounded by DialTimeout.
+func lookupAddrs(ctx context.Context, option *WatcherOptions) ([]string, error) {
+	timeout := option.DialTimeout
+	if timeout <= 0 {
+		timeout = defaultDialTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	addrs, err := option.Discover(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("discovering the Redis addresses: %w", err)
+	}
+	if len(addrs) == 0 {
+		return nil, ErrNoAddrs
+	}
+	return addrs, nil
+}
+
+// setAddrs sets addrs as the seed nodes of a cluster, the sentinels of a
+// failover deployment or, for a single node, the address of Options unless
+// it is still one of addrs. It reports whether the addresses changed.
+func setAddrs(option *WatcherOptions, addrs []string) bool {
+	switch {
+	case len(option.ClusterAddrs) > 0:
+		if sameAddrs(option.ClusterAddrs, addrs) {
+			return false
+		}
+		option.ClusterAddrs = addrs
+	case option.MasterName != "":
+		if sameAddrs(option.SentinelAddrs, addrs) {
+			return false
+		}
+		option.SentinelAddrs = addrs
+	default:
+		for _, addr := range addrs {
+			if addr == option.Addr {
+				return false
+			}
+		}
+		option.Addr = addrs[0]
+	}
+	return true
+}
+
+// sameAddrs reports whether a and b hold the same addresses in any order.
+func sameAddrs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// startDiscovery starts refreshing the addresses every DiscoveryInterval.
+func (w *Watcher) startDiscovery() {
+	if w.options.Discover == nil || w.options.DiscoveryInterval <= 0 {
+		return
+	}
+	closed, ctx := w.close, w.ctx
+	w.start(func() {
+		ticker := time.NewTicker(w.options.DiscoveryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-closed:
+				return
+			case <-ticker.C:
+			}
+			if w.rediscover(ctx) {
+				return
+			}
+		}
+	})
+}
+
+// rediscover looks the addresses up again and, when they changed, restarts
+// the watcher with clients connecting to the new ones. It reports whether
+// it did, the restart starting a new discovery loop.
+func (w *Watcher) rediscover(ctx context.Context) bool {
+	addrs, err := lookupAddrs(ctx, &w.options)
+	if err != nil {
+		if !w.closed() {
+			w.reportError(err)
+		}
+		return false
+	}
+	w.l.Lock()
+	changed := setAddrs(&w.options, addrs)
+	w.l.Unlock()
+	if !changed {
+		return false
+	}
+	w.logger().Info(fmt.Sprintf("reconnecting to the discovered Redis addresses %v", addrs))
+	// Restart waits for this loop, so it runs on its own goroutine.
+	go func() {
+		if err := w.restart(context.Background(), true, nil); err != nil && !errors.Is(err, ErrClosed) {
+			w.reportError(err)
+		}
+	}()
+	return true
+}
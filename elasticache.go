@@ -0,0 +1,48 @@
+package rediswatcher
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+)
+
+// defaultRedisPort is the port of a ConfigurationEndpoint given without
+// one.
+const defaultRedisPort = "6379"
+
+// applyConfigurationEndpoint connects to the cluster behind the
+// ConfigurationEndpoint, with TLS unless DisableTLS is set.
+func applyConfigurationEndpoint(option *WatcherOptions) error {
+	if option.ConfigurationEndpoint == "" {
+		if option.DisableTLS {
+			return errors.New("DisableTLS requires a ConfigurationEndpoint")
+		}
+		return nil
+	}
+	if len(option.ClusterAddrs) > 0 || option.MasterName != "" {
+		return errors.New("ConfigurationEndpoint cannot be combined with ClusterAddrs or MasterName")
+	}
+	endpoint := option.ConfigurationEndpoint
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		host = endpoint
+		endpoint = net.JoinHostPort(host, defaultRedisPort)
+	}
+	option.ClusterAddrs = []string{endpoint}
+	if option.DisableTLS {
+		if option.TLSConfig != nil {
+			return errors.New("DisableTLS cannot be combined with TLSConfig")
+		}
+		if option.Password != "" {
+			return errors.New("an auth token requires TLS")
+		}
+		return nil
+	}
+	if option.TLSConfig == nil {
+		// The node certificates are issued for a wildcard covering the
+		// configuration endpoint, so it verifies the nodes too, including
+		// the ones announced by IP address.
+		option.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12, ServerName: host}
+	}
+	return nil
+}
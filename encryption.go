@@ -0,0 +1,81 @@
+package rediswatcher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// defaultEncryptionKeyID identifies EncryptionKey when EncryptionKeyID is
+// not set.
+const defaultEncryptionKeyID = "default"
+
+// validateEncryption checks the encryption keys and fills in defaults.
+func validateEncryption(option *WatcherOptions) error {
+	if option.EncryptionKey == nil {
+		if len(option.DecryptionKeys) > 0 {
+			return errors.New("DecryptionKeys require an EncryptionKey")
+		}
+		return nil
+	}
+	if option.EncryptionKeyID == "" {
+		option.EncryptionKeyID = defaultEncryptionKeyID
+	}
+	if _, err := aes.NewCipher(option.EncryptionKey); err != nil {
+		return fmt.Errorf("invalid EncryptionKey: %w", err)
+	}
+	for id, key := range option.DecryptionKeys {
+		if _, err := aes.NewCipher(key); err != nil {
+			return fmt.Errorf("invalid decryption key %q: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt encrypts the payload of the envelope m with EncryptionKey. The
+// random nonce is prepended to the ciphertext and the routing fields are
+// authenticated as additional data.
+func (w *Watcher) encrypt(m *MSG) error {
+	gcm, err := newGCM(w.options.EncryptionKey)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	m.KeyID = w.options.EncryptionKeyID
+	m.Payload = gcm.Seal(nonce, nonce, m.Payload, canonical(m, false))
+	return nil
+}
+
+// decrypt decrypts the payload of the envelope m with the key named by its
+// KeyID, which is EncryptionKey or one of DecryptionKeys.
+func (w *Watcher) decrypt(m *MSG) ([]byte, error) {
+	key := w.options.DecryptionKeys[m.KeyID]
+	if m.KeyID == w.options.EncryptionKeyID {
+		key = w.options.EncryptionKey
+	}
+	if key == nil {
+		return nil, fmt.Errorf("dropping %s message from %s encrypted with unknown key %q", m.Method, m.ID, m.KeyID)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Payload) < gcm.NonceSize() {
+		return nil, errors.New("encrypted payload is too short")
+	}
+	nonce, ciphertext := m.Payload[:gcm.NonceSize()], m.Payload[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, canonical(m, false))
+}
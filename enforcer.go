@@ -0,0 +1,187 @@
+package rediswatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// NewWatcherWithEnforcer creates a Watcher like NewWatcher and binds it to e,
+// see Bind.
+func NewWatcherWithEnforcer(addr string, option WatcherOptions, e casbin.IEnforcer) (persist.Watcher, error) {
+	w, err := NewWatcher(addr, option)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.(*Watcher).Bind(e); err != nil {
+		w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Bind sets the watcher of e and keeps e in sync with the messages received.
+// A *casbin.Enforcer applies the changed rules incrementally, replaces its
+// policy with the model carried by UpdateForSavePolicy and only reloads the
+// whole policy from its adapter for Update, so that a large fleet does not
+// query the database for every change. The update methods, whose messages
+// do not carry the section and policy type, apply to the single policy type
+// holding the old rules. Other enforcers, e.g.
+// casbin.SyncedEnforcer, reload the policy for every message, as their model
+// cannot be changed safely from outside. The policy is also reloaded when a
+// change cannot be applied.
+//
+// Use IgnoreSelf to keep e from applying its own changes a second time.
+func (w *Watcher) Bind(e casbin.IEnforcer) error {
+	if err := e.SetWatcher(w); err != nil {
+		return err
+	}
+	enforcer, ok := e.(*casbin.Enforcer)
+	if !ok {
+		return nil
+	}
+	return w.SetHandlers(enforcerHandlers(w, enforcer))
+}
+
+// enforcerHandlers returns the handlers applying the messages to e, see
+// Bind.
+func enforcerHandlers(w *Watcher, enforcer *casbin.Enforcer) Handlers {
+	return Handlers{
+		OnAddPolicy: func(_ context.Context, sec, ptype string, rule []string) error {
+			return addPolicies(enforcer, sec, ptype, [][]string{rule})
+		},
+		OnRemovePolicy: func(_ context.Context, sec, ptype string, rule []string) error {
+			return removePolicies(enforcer, sec, ptype, [][]string{rule})
+		},
+		OnRemoveFilteredPolicy: func(_ context.Context, sec, ptype string, fieldIndex int, fieldValues []string) error {
+			if err := checkAssertion(enforcer, sec, ptype); err != nil {
+				return err
+			}
+			_, effects := enforcer.GetModel().RemoveFilteredPolicy(sec, ptype, fieldIndex, fieldValues...)
+			return buildRoleLinks(enforcer, model.PolicyRemove, sec, ptype, effects)
+		},
+		OnAddPolicies: func(_ context.Context, sec, ptype string, rules [][]string) error {
+			return addPolicies(enforcer, sec, ptype, rules)
+		},
+		OnRemovePolicies: func(_ context.Context, sec, ptype string, rules [][]string) error {
+			return removePolicies(enforcer, sec, ptype, rules)
+		},
+		OnSavePolicy: func(_ context.Context, m model.Model) error {
+			return replacePolicy(enforcer, m)
+		},
+		OnUpdatePolicy: func(_ context.Context, oldRule, newRule []string) error {
+			return updatePolicies(enforcer, [][]string{oldRule}, [][]string{newRule})
+		},
+		OnUpdatePolicies: func(_ context.Context, oldRules, newRules [][]string) error {
+			return updatePolicies(enforcer, oldRules, newRules)
+		},
+		OnError: func(msg *MSG, err error) {
+			w.logger().Warn(fmt.Sprintf("reloading policy after failing to apply %s: %v", msg.Method, err))
+			if err := enforcer.LoadPolicy(); err != nil {
+				w.reportError(err)
+			}
+		},
+	}
+}
+
+func addPolicies(e *casbin.Enforcer, sec, ptype string, rules [][]string) error {
+	if err := checkAssertion(e, sec, ptype); err != nil {
+		return err
+	}
+	affected := e.GetModel().AddPoliciesWithAffected(sec, ptype, rules)
+	return buildRoleLinks(e, model.PolicyAdd, sec, ptype, affected)
+}
+
+func removePolicies(e *casbin.Enforcer, sec, ptype string, rules [][]string) error {
+	if err := checkAssertion(e, sec, ptype); err != nil {
+		return err
+	}
+	effected := e.GetModel().RemovePoliciesWithEffected(sec, ptype, rules)
+	return buildRoleLinks(e, model.PolicyRemove, sec, ptype, effected)
+}
+
+// replacePolicy replaces the policy of e with the policy of m, as loading
+// it from the adapter would after the publisher saved it.
+func replacePolicy(e *casbin.Enforcer, m model.Model) error {
+	if len(m["p"]) == 0 {
+		return errors.New("model carries no policy")
+	}
+	for _, sec := range []string{"p", "g"} {
+		for ptype := range m[sec] {
+			if err := checkAssertion(e, sec, ptype); err != nil {
+				return err
+			}
+		}
+	}
+	e.GetModel().ClearPolicy()
+	for _, sec := range []string{"p", "g"} {
+		for ptype, a := range m[sec] {
+			e.GetModel().AddPolicies(sec, ptype, a.Policy)
+		}
+	}
+	// Rebuild the role links even when m carries no grouping rules, so that
+	// the links of the rules just cleared do not outlive them.
+	if len(e.GetModel()["g"]) == 0 {
+		return nil
+	}
+	return e.BuildRoleLinks()
+}
+
+// updatePolicies replaces oldRules with newRules in the one policy type of
+// e holding all of oldRules.
+func updatePolicies(e *casbin.Enforcer, oldRules, newRules [][]string) error {
+	if len(oldRules) != len(newRules) {
+		return errors.New("the numbers of old and new rules differ")
+	}
+	var found [][2]string
+	for _, sec := range []string{"p", "g"} {
+		for ptype := range e.GetModel()[sec] {
+			if hasAllPolicies(e.GetModel(), sec, ptype, oldRules) {
+				found = append(found, [2]string{sec, ptype})
+			}
+		}
+	}
+	if len(found) != 1 {
+		return fmt.Errorf("the old rules belong to %d policy types instead of one", len(found))
+	}
+	sec, ptype := found[0][0], found[0][1]
+	if !e.GetModel().UpdatePolicies(sec, ptype, oldRules, newRules) {
+		return errors.New("the rules could not be updated")
+	}
+	if err := buildRoleLinks(e, model.PolicyRemove, sec, ptype, oldRules); err != nil {
+		return err
+	}
+	return buildRoleLinks(e, model.PolicyAdd, sec, ptype, newRules)
+}
+
+// hasAllPolicies reports whether m holds every rule of rules in sec and
+// ptype, as Model.HasPolicies reports whether it holds any.
+func hasAllPolicies(m model.Model, sec, ptype string, rules [][]string) bool {
+	for _, rule := range rules {
+		if !m.HasPolicy(sec, ptype, rule) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkAssertion checks that the model of e defines ptype in sec, as the
+// model methods do not.
+func checkAssertion(e *casbin.Enforcer, sec, ptype string) error {
+	if _, ok := e.GetModel()[sec][ptype]; !ok {
+		return fmt.Errorf("policy type %s.%s is not defined in the model", sec, ptype)
+	}
+	return nil
+}
+
+// buildRoleLinks updates the role links of e for changed grouping rules.
+func buildRoleLinks(e *casbin.Enforcer, op model.PolicyOp, sec, ptype string, rules [][]string) error {
+	if sec != "g" || len(rules) == 0 {
+		return nil
+	}
+	return e.BuildIncrementalRoleLinks(op, ptype, rules)
+}
@@ -0,0 +1,189 @@
+package rediswatcher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+)
+
+// EnforcerMetadataKey is the MSG.Metadata key naming the enforcer a message
+// was published for, see BindEnforcers.
+const EnforcerMetadataKey = "enforcer"
+
+// EnforcerWatcher is the watcher of one of the enforcers bound with
+// BindEnforcers. It publishes through the Watcher it was created from,
+// naming its enforcer in the metadata of the messages, and implements
+// persist.Watcher, persist.WatcherEx and persist.WatcherUpdatable.
+type EnforcerWatcher struct {
+	w        *Watcher
+	name     string
+	handlers *Handlers
+	callback func(string)
+}
+
+// enforcerRouter routes the messages received to the enforcers bound with
+// BindEnforcers.
+type enforcerRouter struct {
+	route     func(msg *MSG) string
+	enforcers map[string]*EnforcerWatcher
+}
+
+// BindEnforcers binds several enforcers to w, e.g. one per domain or model,
+// so that they share its connections. Each enforcer is kept in sync as with
+// Bind, but only with the messages that route maps to its name. A nil route
+// routes by the EnforcerMetadataKey metadata, which the watchers of the
+// enforcers set. Messages routed to no bound enforcer, e.g. the Update
+// message of a watcher serving a single enforcer, or the Close notification
+// of a peer, are passed to all of them.
+//
+// The routing replaces the handlers and update callbacks of w for messages,
+// and calling BindEnforcers again replaces the enforcers bound before.
+func (w *Watcher) BindEnforcers(enforcers map[string]casbin.IEnforcer, route func(msg *MSG) string) error {
+	if len(enforcers) == 0 {
+		return errors.New("no enforcer to bind")
+	}
+	if route == nil {
+		route = func(msg *MSG) string {
+			return msg.Metadata[EnforcerMetadataKey]
+		}
+	}
+	router := &enforcerRouter{route: route, enforcers: make(map[string]*EnforcerWatcher, len(enforcers))}
+	for name, e := range enforcers {
+		ew := &EnforcerWatcher{w: w, name: name}
+		if err := e.SetWatcher(ew); err != nil {
+			return err
+		}
+		if enforcer, ok := e.(*casbin.Enforcer); ok {
+			h := enforcerHandlers(w, enforcer)
+			ew.handlers = &h
+		}
+		router.enforcers[name] = ew
+	}
+	w.l.Lock()
+	w.router = router
+	w.l.Unlock()
+	return nil
+}
+
+// routeDelivery passes d to the enforcers it is routed to.
+func (w *Watcher) routeDelivery(r *enforcerRouter, d *Delivery) {
+	if d.MSG != nil {
+		if ew, ok := r.enforcers[r.route(d.MSG)]; ok {
+			ew.deliver(d)
+			return
+		}
+	}
+	for _, ew := range r.enforcers {
+		ew.deliver(d)
+	}
+}
+
+// deliver applies d to the enforcer, or passes it to its update callback.
+func (ew *EnforcerWatcher) deliver(d *Delivery) {
+	if d.MSG != nil && ew.w.handleWith(d.Context, ew.handlers, d.MSG) {
+		return
+	}
+	if callback := ew.updateCallback(); callback != nil {
+		callback(d.Payload)
+	}
+}
+
+// Name returns the name the enforcer was bound with.
+func (ew *EnforcerWatcher) Name() string {
+	return ew.name
+}
+
+// SetUpdateCallback sets the callback receiving the messages routed to the
+// enforcer that its handlers do not apply.
+func (ew *EnforcerWatcher) SetUpdateCallback(callback func(string)) error {
+	ew.w.l.Lock()
+	ew.callback = callback
+	ew.w.l.Unlock()
+	return nil
+}
+
+func (ew *EnforcerWatcher) updateCallback() func(string) {
+	ew.w.l.Lock()
+	defer ew.w.l.Unlock()
+	return ew.callback
+}
+
+// Close does nothing: the enforcers share the Watcher, which is closed on
+// its own.
+func (ew *EnforcerWatcher) Close() {}
+
+// publish publishes m, naming the enforcer in its metadata.
+func (ew *EnforcerWatcher) publish(m MSG) error {
+	WithMetadata(map[string]string{EnforcerMetadataKey: ew.name})(&m)
+	return ew.w.logRecord(func() error {
+		ew.w.l.Lock()
+		defer ew.w.l.Unlock()
+		if m.Method == MethodUpdateForSavePolicy && ew.w.options.SavePolicyNotifyOnly {
+			m.Params = nil
+		}
+		return ew.w.publish(m)
+	})
+}
+
+// Update asks the other watchers to reload the policy of the enforcer.
+func (ew *EnforcerWatcher) Update() error {
+	return ew.publish(MSG{Method: MethodUpdate})
+}
+
+// UpdateForAddPolicy is the enforcer counterpart of
+// Watcher.UpdateForAddPolicy.
+func (ew *EnforcerWatcher) UpdateForAddPolicy(sec, ptype string, params ...string) error {
+	return ew.publish(MSG{Method: MethodUpdateForAddPolicy, Sec: sec, Ptype: ptype, Params: params})
+}
+
+// UpdateForRemovePolicy is the enforcer counterpart of
+// Watcher.UpdateForRemovePolicy.
+func (ew *EnforcerWatcher) UpdateForRemovePolicy(sec, ptype string, params ...string) error {
+	return ew.publish(MSG{Method: MethodUpdateForRemovePolicy, Sec: sec, Ptype: ptype, Params: params})
+}
+
+// UpdateForRemoveFilteredPolicy is the enforcer counterpart of
+// Watcher.UpdateForRemoveFilteredPolicy.
+func (ew *EnforcerWatcher) UpdateForRemoveFilteredPolicy(sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	return ew.publish(MSG{
+		Method:      MethodUpdateForRemoveFilteredPolicy,
+		Sec:         sec,
+		Ptype:       ptype,
+		Params:      fmt.Sprintf("%d %s", fieldIndex, strings.Join(fieldValues, " ")),
+		FieldIndex:  fieldIndex,
+		FieldValues: fieldValues,
+	})
+}
+
+// UpdateForSavePolicy is the enforcer counterpart of
+// Watcher.UpdateForSavePolicy.
+func (ew *EnforcerWatcher) UpdateForSavePolicy(model model.Model) error {
+	return ew.publish(MSG{Method: MethodUpdateForSavePolicy, Params: model})
+}
+
+// UpdateForAddPolicies is the enforcer counterpart of
+// Watcher.UpdateForAddPolicies.
+func (ew *EnforcerWatcher) UpdateForAddPolicies(sec, ptype string, rules [][]string) error {
+	return ew.publish(MSG{Method: MethodUpdateForAddPolicies, Sec: sec, Ptype: ptype, Params: rules})
+}
+
+// UpdateForRemovePolicies is the enforcer counterpart of
+// Watcher.UpdateForRemovePolicies.
+func (ew *EnforcerWatcher) UpdateForRemovePolicies(sec, ptype string, rules [][]string) error {
+	return ew.publish(MSG{Method: MethodUpdateForRemovePolicies, Sec: sec, Ptype: ptype, Params: rules})
+}
+
+// UpdateForUpdatePolicy is the enforcer counterpart of
+// Watcher.UpdateForUpdatePolicy.
+func (ew *EnforcerWatcher) UpdateForUpdatePolicy(oldRule, newRule []string) error {
+	return ew.publish(MSG{Method: MethodUpdateForUpdatePolicy, OldRules: [][]string{oldRule}, NewRules: [][]string{newRule}})
+}
+
+// UpdateForUpdatePolicies is the enforcer counterpart of
+// Watcher.UpdateForUpdatePolicies.
+func (ew *EnforcerWatcher) UpdateForUpdatePolicies(oldRules, newRules [][]string) error {
+	return ew.publish(MSG{Method: MethodUpdateForUpdatePolicies, OldRules: oldRules, NewRules: newRules})
+}
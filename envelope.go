@@ -0,0 +1,123 @@
+package rediswatcher
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"strconv"
+)
+
+// encode serializes m and seals it, see seal.
+func (w *Watcher) encode(m *MSG) ([]byte, error) {
+	data, err := w.marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return w.seal(m, data)
+}
+
+// seal wraps data, the encoding of m, into an envelope when it has to be
+// compressed, encrypted or signed. The envelope keeps the fields needed to
+// route the message and carries data, transformed in this order, in Payload.
+func (w *Watcher) seal(m *MSG, data []byte) ([]byte, error) {
+	compress := w.options.Compressor != nil && len(data) > w.options.CompressionThreshold
+	if !compress && w.options.EncryptionKey == nil && w.options.SigningKey == nil {
+		return data, nil
+	}
+	env := MSG{
+		Version:   m.Version,
+		Method:    m.Method,
+		ID:        m.ID,
+		MessageID: m.MessageID,
+		Seq:       m.Seq,
+	}
+	if compress {
+		var err error
+		if data, err = w.options.Compressor.Compress(data); err != nil {
+			return nil, err
+		}
+		env.Encoding = w.options.Compressor.Name()
+	}
+	env.Payload = data
+	if w.options.EncryptionKey != nil {
+		if err := w.encrypt(&env); err != nil {
+			return nil, err
+		}
+	}
+	if w.options.SigningKey != nil {
+		env.Signature = w.signature(&env)
+	}
+	return w.marshal(&env)
+}
+
+// sealed reports whether m is an envelope produced by seal.
+func (m *MSG) sealed() bool {
+	return len(m.Payload) > 0 || m.Encoding != "" || m.KeyID != "" || m.Signature != nil
+}
+
+// open verifies the envelope m and replaces it with the message it wraps.
+// With a SigningKey every message must be a signed envelope.
+func (w *Watcher) open(m *MSG) error {
+	if w.options.SigningKey != nil {
+		if m.Signature == nil {
+			return errors.New("dropping unsigned " + m.Method + " message from " + m.ID)
+		}
+		if !hmac.Equal(m.Signature, w.signature(m)) {
+			return errors.New("dropping " + m.Method + " message from " + m.ID + " with an invalid signature")
+		}
+	}
+	if !m.sealed() {
+		return nil
+	}
+	data := m.Payload
+	if m.KeyID != "" {
+		var err error
+		if data, err = w.decrypt(m); err != nil {
+			return err
+		}
+	}
+	if m.Encoding != "" {
+		c, err := w.compressor(m.Encoding)
+		if err != nil {
+			return err
+		}
+		if data, err = c.Decompress(data); err != nil {
+			return err
+		}
+	}
+	*m = MSG{}
+	return w.options.Serializer.Unmarshal(data, m)
+}
+
+// signature computes the HMAC-SHA256 of the envelope m with SigningKey.
+func (w *Watcher) signature(m *MSG) []byte {
+	mac := hmac.New(sha256.New, w.options.SigningKey)
+	mac.Write(canonical(m, true))
+	return mac.Sum(nil)
+}
+
+// canonical encodes the routing fields of the envelope m, and its payload if
+// requested, in a form that does not depend on the Serializer, for signing
+// and authenticated encryption.
+func canonical(m *MSG, payload bool) []byte {
+	fields := []string{
+		strconv.Itoa(m.Version),
+		m.Method,
+		m.ID,
+		m.MessageID,
+		strconv.FormatInt(m.Seq, 10),
+		m.Encoding,
+		m.KeyID,
+	}
+	if payload {
+		fields = append(fields, string(m.Payload))
+	}
+	var b []byte
+	for _, field := range fields {
+		var n [8]byte
+		binary.BigEndian.PutUint64(n[:], uint64(len(field)))
+		b = append(append(b, n[:]...), field...)
+	}
+	return b
+}
@@ -0,0 +1,48 @@
+package rediswatcher
+
+import (
+	"context"
+	"fmt"
+
+	rds "github.com/redis/go-redis/v9"
+)
+
+// epochKey is the counter numbering the policy versions of channel.
+func epochKey(channel string) string {
+	return hashTag(channel) + ":epoch"
+}
+
+// staleEpoch reports whether msg, received on channel, is not newer than
+// the last message delivered on it, and records its epoch otherwise. It
+// must be called with w.dispatchL held.
+func (w *Watcher) staleEpoch(channel string, msg *MSG) bool {
+	if applied := w.epochs[channel]; msg.Epoch <= applied {
+		w.logger().Debug(fmt.Sprintf("skipping %s message of epoch %d, already at epoch %d", msg.Method, msg.Epoch, applied))
+		return true
+	}
+	if w.epochs == nil {
+		w.epochs = make(map[string]int64)
+	}
+	w.epochs[channel] = msg.Epoch
+	return false
+}
+
+// Epoch returns the current policy epoch of the watcher channel in Redis,
+// see WatcherOptions.PolicyEpoch, e.g. to record the version of a freshly
+// loaded policy.
+func (w *Watcher) Epoch(ctx context.Context) (int64, error) {
+	_, client := w.clients()
+	epoch, err := client.Get(ctx, epochKey(w.options.Channel)).Int64()
+	if err == rds.Nil {
+		return 0, nil
+	}
+	return epoch, err
+}
+
+// AppliedEpoch returns the epoch of the last message delivered on the
+// watcher channel.
+func (w *Watcher) AppliedEpoch() int64 {
+	w.dispatchL.Lock()
+	defer w.dispatchL.Unlock()
+	return w.epochs[w.options.Channel]
+}
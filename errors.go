@@ -0,0 +1,37 @@
+package rediswatcher
+
+// errorsBuffer is the capacity of the channel returned by Errors.
+const errorsBuffer = 16
+
+// SetErrorCallback sets a function called with the errors that occur in the
+// background, e.g. while receiving or decoding messages, reconnecting or
+// closing connections. Errors returned to the caller, such as publish
+// failures, are not reported. The callback must not block.
+func (w *Watcher) SetErrorCallback(callback func(error)) {
+	w.errL.Lock()
+	w.errorCallback = callback
+	w.errL.Unlock()
+}
+
+// Errors returns a channel receiving the errors that occur in the
+// background, see SetErrorCallback. Errors are dropped while the channel is
+// full. The channel is never closed.
+func (w *Watcher) Errors() <-chan error {
+	return w.errs
+}
+
+// reportError logs an error that occurred in the background and passes it to
+// the error callback and the Errors channel.
+func (w *Watcher) reportError(err error) {
+	w.logger().Error(err)
+	w.errL.Lock()
+	callback := w.errorCallback
+	w.errL.Unlock()
+	if callback != nil {
+		callback(err)
+	}
+	select {
+	case w.errs <- err:
+	default:
+	}
+}
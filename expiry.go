@@ -0,0 +1,23 @@
+package rediswatcher
+
+import (
+	"fmt"
+	"time"
+)
+
+// expired reports whether msg was published longer than MaxMessageAge ago,
+// counting it in Metrics if so.
+func (w *Watcher) expired(msg *MSG) bool {
+	if w.options.MaxMessageAge <= 0 || msg.Timestamp == 0 {
+		return false
+	}
+	age := time.Since(time.UnixMilli(msg.Timestamp))
+	if age <= w.options.MaxMessageAge {
+		return false
+	}
+	w.logger().Debug(fmt.Sprintf("dropping %s message published %s ago", msg.Method, age.Round(time.Millisecond)))
+	if r, ok := w.options.Metrics.(ExpiryRecorder); ok {
+		r.Expired(msg.Method)
+	}
+	return true
+}
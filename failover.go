@@ -0,0 +1,118 @@
+package rediswatcher
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	rds "github.com/redis/go-redis/v9"
+)
+
+// switchMasterChannel is the channel the sentinels announce failovers on,
+// with payloads "<master name> <old ip> <old port> <new ip> <new port>".
+const switchMasterChannel = "+switch-master"
+
+// watchesFailover reports whether the watcher follows the failovers of a
+// Sentinel managed master, see watchFailover.
+func watchesFailover(option *WatcherOptions) bool {
+	return option.MasterName != "" && len(option.SentinelAddrs) > 0 && option.Transport != TransportStream && option.Transport != TransportPoll
+}
+
+// watchFailover listens for the failovers of MasterName announced by the
+// sentinels and re-creates the subscriptions when one happens, rather than
+// waiting for the subscription on the demoted master to fail, which it may
+// do silently. It moves to the next sentinel when the current one fails.
+func (w *Watcher) watchFailover() {
+	closed := w.close
+	backoff := w.options.ReconnectMinBackoff
+	for i := 0; ; i++ {
+		addr := w.options.SentinelAddrs[i%len(w.options.SentinelAddrs)]
+		client := rds.NewSentinelClient(sentinelOptions(&w.options, addr))
+		err := w.listenSentinel(client, closed, func() { backoff = w.options.ReconnectMinBackoff })
+		_ = client.Close()
+		select {
+		case <-closed:
+			return
+		default:
+		}
+		w.reportError(fmt.Errorf("sentinel %s: %w", addr, err))
+		select {
+		case <-closed:
+			return
+		case <-time.After(jitter(backoff)):
+		}
+		if backoff *= 2; backoff > w.options.ReconnectMaxBackoff {
+			backoff = w.options.ReconnectMaxBackoff
+		}
+	}
+}
+
+// listenSentinel handles the failover announcements of the sentinel of
+// client until it fails or closed is closed, calling subscribed once it
+// listens.
+func (w *Watcher) listenSentinel(client *rds.SentinelClient, closed chan struct{}, subscribed func()) error {
+	sub := client.Subscribe(w.ctx, switchMasterChannel)
+	done := make(chan struct{})
+	defer close(done)
+	// Closing sub interrupts a blocked receive.
+	go func() {
+		select {
+		case <-closed:
+		case <-done:
+		}
+		_ = sub.Close()
+	}()
+	if _, err := sub.Receive(w.ctx); err != nil {
+		return err
+	}
+	subscribed()
+	pinged := false
+	for {
+		msg, err := sub.ReceiveTimeout(w.ctx, healthCheckInterval)
+		if err != nil {
+			var netErr net.Error
+			if !pinged && errors.As(err, &netErr) && netErr.Timeout() {
+				pinged = true
+				if err = sub.Ping(w.ctx); err == nil {
+					continue
+				}
+			}
+			return err
+		}
+		pinged = false
+		m, ok := msg.(*rds.Message)
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(m.Payload)
+		if len(fields) != 5 || fields[0] != w.options.MasterName {
+			continue
+		}
+		w.logger().Info(fmt.Sprintf("master %s failed over to %s, resubscribing", fields[0], net.JoinHostPort(fields[3], fields[4])))
+		w.l.Lock()
+		subs := w.subs
+		w.l.Unlock()
+		for _, s := range subs {
+			s.reset()
+		}
+	}
+}
+
+// sentinelOptions are the options of the connection to the sentinel at
+// addr, authenticated with SentinelUsername and SentinelPassword as the
+// failover client does.
+func sentinelOptions(option *WatcherOptions, addr string) *rds.Options {
+	o := &option.Options
+	return &rds.Options{
+		Addr:        addr,
+		Username:    option.SentinelUsername,
+		Password:    option.SentinelPassword,
+		TLSConfig:   o.TLSConfig,
+		Dialer:      o.Dialer,
+		OnConnect:   o.OnConnect,
+		DialTimeout: o.DialTimeout,
+		MaxRetries:  o.MaxRetries,
+	}
+}
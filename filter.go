@@ -0,0 +1,22 @@
+package rediswatcher
+
+// filtered reports whether msg is skipped by WatcherOptions.Sections,
+// Ptypes or Filter.
+func (w *Watcher) filtered(msg *MSG) bool {
+	if msg.Sec != "" && len(w.options.Sections) > 0 && !contains(w.options.Sections, msg.Sec) {
+		return true
+	}
+	if msg.Ptype != "" && len(w.options.Ptypes) > 0 && !contains(w.options.Ptypes, msg.Ptype) {
+		return true
+	}
+	return w.options.Filter != nil && !w.options.Filter(msg)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -1,9 +1,15 @@
 module github.com/casbin/redis-watcher/v2
 
-go 1.14
+go 1.18
 
 require (
 	github.com/casbin/casbin/v2 v2.30.0
-	github.com/go-redis/redis/v8 v8.8.0
 	github.com/google/uuid v1.2.0
+	github.com/redis/go-redis/v9 v9.7.3
+)
+
+require (
+	github.com/Knetic/govaluate v3.0.1-0.20171022003610-9aa49832a739+incompatible // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
 )
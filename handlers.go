@@ -0,0 +1,113 @@
+package rediswatcher
+
+import (
+	"context"
+	"errors"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// Handlers are typed callbacks receiving the decoded arguments of each
+// message method, set with SetHandlers. A message whose method has no
+// handler is passed to the update callback as before. Handler errors, and
+// messages that cannot be decoded, are passed to OnError, or reported like
+// other background errors when OnError is nil, see SetErrorCallback.
+//
+// The handlers receive the context of the callbacks, see
+// SetUpdateCallbackWithContext, which also carries the metadata of the
+// message, see MetadataFromContext.
+type Handlers struct {
+	OnUpdate               func(ctx context.Context) error
+	OnAddPolicy            func(ctx context.Context, sec, ptype string, rule []string) error
+	OnRemovePolicy         func(ctx context.Context, sec, ptype string, rule []string) error
+	OnRemoveFilteredPolicy func(ctx context.Context, sec, ptype string, fieldIndex int, fieldValues []string) error
+	OnSavePolicy           func(ctx context.Context, m model.Model) error
+	OnAddPolicies          func(ctx context.Context, sec, ptype string, rules [][]string) error
+	OnRemovePolicies       func(ctx context.Context, sec, ptype string, rules [][]string) error
+	OnUpdatePolicy         func(ctx context.Context, oldRule, newRule []string) error
+	OnUpdatePolicies       func(ctx context.Context, oldRules, newRules [][]string) error
+	OnError                func(msg *MSG, err error)
+}
+
+// SetHandlers sets the typed callbacks, replacing the ones set before.
+func (w *Watcher) SetHandlers(handlers Handlers) error {
+	w.l.Lock()
+	w.handlers = &handlers
+	w.l.Unlock()
+	return nil
+}
+
+// handle passes msg to its typed handler with ctx and reports whether
+// there was one.
+func (w *Watcher) handle(ctx context.Context, msg *MSG) bool {
+	return w.handleWith(ctx, w.handlers, msg)
+}
+
+// handleWith passes msg to its handler in h with ctx and reports whether
+// there was one.
+func (w *Watcher) handleWith(ctx context.Context, h *Handlers, msg *MSG) bool {
+	if h == nil {
+		return false
+	}
+	ctx = contextWithMetadata(ctx, msg.Metadata)
+	var err error
+	switch {
+	case msg.Method == MethodUpdate && h.OnUpdate != nil:
+		err = h.OnUpdate(ctx)
+	case msg.Method == MethodUpdateForAddPolicy && h.OnAddPolicy != nil:
+		var rule []string
+		if rule, err = msg.PolicyParams(); err == nil {
+			err = h.OnAddPolicy(ctx, msg.Sec, msg.Ptype, rule)
+		}
+	case msg.Method == MethodUpdateForRemovePolicy && h.OnRemovePolicy != nil:
+		var rule []string
+		if rule, err = msg.PolicyParams(); err == nil {
+			err = h.OnRemovePolicy(ctx, msg.Sec, msg.Ptype, rule)
+		}
+	case msg.Method == MethodUpdateForRemoveFilteredPolicy && h.OnRemoveFilteredPolicy != nil:
+		var fieldIndex int
+		var fieldValues []string
+		if fieldIndex, fieldValues, err = msg.RemoveFilteredPolicyParams(); err == nil {
+			err = h.OnRemoveFilteredPolicy(ctx, msg.Sec, msg.Ptype, fieldIndex, fieldValues)
+		}
+	case msg.NotifyOnly():
+		// Without the model, the policy has to be reloaded as for Update.
+		if h.OnUpdate == nil {
+			return false
+		}
+		err = h.OnUpdate(ctx)
+	case msg.Method == MethodUpdateForSavePolicy && h.OnSavePolicy != nil:
+		var m model.Model
+		if m, err = msg.ModelParams(); err == nil {
+			err = h.OnSavePolicy(ctx, m)
+		}
+	case msg.Method == MethodUpdateForAddPolicies && h.OnAddPolicies != nil:
+		var rules [][]string
+		if rules, err = msg.PoliciesParams(); err == nil {
+			err = h.OnAddPolicies(ctx, msg.Sec, msg.Ptype, rules)
+		}
+	case msg.Method == MethodUpdateForRemovePolicies && h.OnRemovePolicies != nil:
+		var rules [][]string
+		if rules, err = msg.PoliciesParams(); err == nil {
+			err = h.OnRemovePolicies(ctx, msg.Sec, msg.Ptype, rules)
+		}
+	case msg.Method == MethodUpdateForUpdatePolicy && h.OnUpdatePolicy != nil:
+		if len(msg.OldRules) != 1 || len(msg.NewRules) != 1 {
+			err = errors.New("malformed UpdateForUpdatePolicy message")
+		} else {
+			err = h.OnUpdatePolicy(ctx, msg.OldRules[0], msg.NewRules[0])
+		}
+	case msg.Method == MethodUpdateForUpdatePolicies && h.OnUpdatePolicies != nil:
+		err = h.OnUpdatePolicies(ctx, msg.OldRules, msg.NewRules)
+	default:
+		return false
+	}
+	if err != nil {
+		if h.OnError != nil {
+			h.OnError(msg, err)
+		} else {
+			w.reportError(err)
+		}
+	}
+	return true
+}
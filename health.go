@@ -0,0 +1,80 @@
+package rediswatcher
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrClosed is returned by Ping once the watcher was closed.
+var ErrClosed = errors.New("watcher is closed")
+
+// HealthStatus is the state of the connections of a watcher, see Healthy.
+// The fields are nil when healthy.
+type HealthStatus struct {
+	// Closed reports whether Close was called.
+	Closed bool
+	// Publisher and Subscriber are the errors pinging the publishing and
+	// the subscribing client. Subscriber is always nil for a watcher
+	// created with NewPublishWatcher.
+	Publisher  error
+	Subscriber error
+	// Subscription is the error of a subscription, or of the stream reader,
+	// that failed and has not recovered yet.
+	Subscription error
+}
+
+// Err returns the first problem of s, or nil if the watcher is healthy.
+func (s HealthStatus) Err() error {
+	switch {
+	case s.Closed:
+		return ErrClosed
+	case s.Publisher != nil:
+		return s.Publisher
+	case s.Subscriber != nil:
+		return s.Subscriber
+	default:
+		return s.Subscription
+	}
+}
+
+// Healthy pings the Redis clients of the watcher and checks that its
+// subscriptions are active, e.g. for a readiness probe.
+func (w *Watcher) Healthy(ctx context.Context) HealthStatus {
+	status := HealthStatus{Closed: w.closed()}
+	if status.Closed {
+		return status
+	}
+	sub, pub := w.clients()
+	status.Publisher = pub.Ping(ctx).Err()
+	if sub != nil {
+		status.Subscriber = sub.Ping(ctx).Err()
+	}
+	status.Subscription = w.subscriptionErr()
+	return status
+}
+
+// subscriptionErr returns the error of a subscription, or of the stream
+// reader, that failed and has not recovered yet.
+func (w *Watcher) subscriptionErr() error {
+	w.errL.Lock()
+	err := w.streamErr
+	w.errL.Unlock()
+	if err != nil {
+		return err
+	}
+	w.l.Lock()
+	subs := w.subs
+	w.l.Unlock()
+	for _, s := range subs {
+		if err := s.lastErr(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ping returns nil if the watcher is healthy and the first problem found by
+// Healthy otherwise.
+func (w *Watcher) Ping(ctx context.Context) error {
+	return w.Healthy(ctx).Err()
+}
@@ -0,0 +1,60 @@
+package rediswatcher
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Heartbeat is published on WatcherOptions.HeartbeatChannel by a watcher
+// with a HeartbeatInterval, encoded as JSON.
+type Heartbeat struct {
+	ID      string    `json:"id"`
+	Channel string    `json:"channel"`
+	Time    time.Time `json:"time"`
+}
+
+// validateHeartbeat defaults and checks the heartbeat channel.
+func validateHeartbeat(option *WatcherOptions) error {
+	if option.HeartbeatInterval <= 0 {
+		return nil
+	}
+	if option.HeartbeatChannel == "" {
+		option.HeartbeatChannel = option.Channel + ":heartbeat"
+	}
+	channel, err := normalizeChannel(option.HeartbeatChannel)
+	if err != nil {
+		return err
+	}
+	option.HeartbeatChannel = channel
+	return nil
+}
+
+// heartbeat periodically publishes a Heartbeat while the subscriptions are
+// up.
+func (w *Watcher) heartbeat() {
+	ticker := time.NewTicker(w.options.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		w.beat()
+		select {
+		case <-w.close:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// beat publishes a single Heartbeat.
+func (w *Watcher) beat() {
+	if w.subscriptionErr() != nil {
+		return
+	}
+	data, err := json.Marshal(Heartbeat{ID: w.options.LocalID, Channel: w.options.Channel, Time: time.Now()})
+	if err != nil {
+		w.reportError(err)
+		return
+	}
+	if err := w.pubClient.Publish(w.ctx, w.options.HeartbeatChannel, data).Err(); err != nil && !w.closed() {
+		w.reportError(err)
+	}
+}
@@ -0,0 +1,162 @@
+package rediswatcher
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// iamTokenExpiry is how long an IAM authentication token is valid, the
+	// longest ElastiCache accepts.
+	iamTokenExpiry = 15 * time.Minute
+	// defaultIAMRefreshInterval renews the token well before it expires.
+	defaultIAMRefreshInterval = 10 * time.Minute
+	// emptyPayloadHash is the SHA-256 of the empty payload of the signed
+	// connect request.
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// AWSCredentials are the credentials IAM authentication tokens are signed
+// with. SessionToken is set for temporary credentials, e.g. of a role.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// IAMAuth authenticates to ElastiCache with IAM instead of a static
+// password, see WatcherOptions.IAMAuth.
+type IAMAuth struct {
+	// UserID is the ElastiCache user, which must have IAM authentication
+	// enabled. It is also the Redis username.
+	UserID string
+	// CacheName is the replication group ID, or the name of a serverless
+	// cache with Serverless set, and Region the AWS region of the cache.
+	CacheName  string
+	Region     string
+	Serverless bool
+	// Credentials returns the AWS credentials signing the tokens, e.g.
+	// from the AWS SDK credentials chain. It is called for every token, so
+	// that rotated credentials are used.
+	Credentials func(ctx context.Context) (AWSCredentials, error)
+	// TokenProvider, when set, returns the tokens instead of signing them
+	// with Credentials, e.g. with the generator of the AWS SDK.
+	TokenProvider func(ctx context.Context) (string, error)
+}
+
+// applyIAMAuth makes the clients authenticate with IAM tokens, renewed
+// every CredentialsRefreshInterval, 10 minutes by default.
+func applyIAMAuth(option *WatcherOptions) error {
+	auth := option.IAMAuth
+	if auth == nil {
+		return nil
+	}
+	switch {
+	case auth.UserID == "":
+		return errors.New("IAMAuth requires a UserID")
+	case auth.TokenProvider == nil && (auth.CacheName == "" || auth.Region == "" || auth.Credentials == nil):
+		return errors.New("IAMAuth requires a TokenProvider or a CacheName, Region and Credentials")
+	case option.Password != "" || option.CredentialsProvider != nil || option.CredentialsProviderContext != nil:
+		return errors.New("IAMAuth cannot be combined with a Password or a credentials provider")
+	case option.TLSConfig == nil:
+		return errors.New("IAM authentication requires TLS")
+	}
+	option.CredentialsProviderContext = func(ctx context.Context) (string, string, error) {
+		token, err := auth.token(ctx)
+		return auth.UserID, token, err
+	}
+	if option.CredentialsRefreshInterval == 0 {
+		option.CredentialsRefreshInterval = defaultIAMRefreshInterval
+	}
+	if option.CredentialsRefreshInterval >= iamTokenExpiry {
+		return errors.New("CredentialsRefreshInterval must be shorter than the 15 minutes IAM tokens are valid")
+	}
+	return nil
+}
+
+// token returns a new authentication token.
+func (a *IAMAuth) token(ctx context.Context) (string, error) {
+	if a.TokenProvider != nil {
+		return a.TokenProvider(ctx)
+	}
+	credentials, err := a.Credentials(ctx)
+	if err != nil {
+		return "", err
+	}
+	return presignIAMToken(a, credentials, time.Now()), nil
+}
+
+// presignIAMToken signs the connect request of a with AWS Signature
+// Version 4 as a presigned URL, which without its scheme is the token.
+func presignIAMToken(a *IAMAuth, credentials AWSCredentials, now time.Time) string {
+	now = now.UTC()
+	date := now.Format("20060102")
+	scope := date + "/" + a.Region + "/elasticache/aws4_request"
+	query := map[string]string{
+		"Action":              "connect",
+		"User":                a.UserID,
+		"X-Amz-Algorithm":     "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":    credentials.AccessKeyID + "/" + scope,
+		"X-Amz-Date":          now.Format("20060102T150405Z"),
+		"X-Amz-Expires":       "900",
+		"X-Amz-SignedHeaders": "host",
+	}
+	if a.Serverless {
+		query["ResourceType"] = "ServerlessCache"
+	}
+	if credentials.SessionToken != "" {
+		query["X-Amz-Security-Token"] = credentials.SessionToken
+	}
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	params := make([]string, 0, len(keys))
+	for _, key := range keys {
+		params = append(params, sigV4Escape(key)+"="+sigV4Escape(query[key]))
+	}
+	canonicalQuery := strings.Join(params, "&")
+
+	canonicalRequest := strings.Join([]string{
+		"GET",
+		"/",
+		canonicalQuery,
+		"host:" + a.CacheName + "\n",
+		"host",
+		emptyPayloadHash,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		query["X-Amz-Date"],
+		scope,
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), date)
+	for _, part := range []string{a.Region, "elasticache", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	return a.CacheName + "/?" + canonicalQuery + "&X-Amz-Signature=" + signature
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// sigV4Escape percent-encodes s as Signature Version 4 requires, every
+// byte but the unreserved characters of RFC 3986.
+func sigV4Escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
@@ -0,0 +1,66 @@
+package rediswatcher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	rds "github.com/redis/go-redis/v9"
+)
+
+// keyspacePattern returns the keyspace notification pattern for the keys
+// matched by KeyspacePattern in the configured database.
+func keyspacePattern(option *WatcherOptions) string {
+	return fmt.Sprintf("__keyspace@%d__:%s", option.DB, option.KeyspacePattern)
+}
+
+// validateKeyspace checks that keyspace notifications can be received with
+// the configured transport.
+func validateKeyspace(option *WatcherOptions) error {
+	if option.KeyspaceAdapter && option.KeyspacePattern != "" {
+		return errors.New("KeyspaceAdapter and KeyspacePattern are mutually exclusive")
+	}
+	if (option.KeyspacePattern != "" || option.KeyspaceAdapter) && option.Transport == TransportStream {
+		return errors.New("keyspace notifications are not supported with the stream transport")
+	}
+	return nil
+}
+
+// applyKeyspaceAdapter sets KeyspacePattern to the key of the Adapter of
+// the final channel, with the glob characters escaped.
+func applyKeyspaceAdapter(option *WatcherOptions) {
+	if !option.KeyspaceAdapter {
+		return
+	}
+	var b strings.Builder
+	for _, r := range policyKey(option.Channel) {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	option.KeyspacePattern = b.String()
+}
+
+// subscribeKeyspace adds the keyspace notification pattern to sub.
+func (w *Watcher) subscribeKeyspace(sub *rds.PubSub) error {
+	if w.options.KeyspacePattern == "" {
+		return nil
+	}
+	if err := sub.PSubscribe(w.ctx, keyspacePattern(&w.options)); err != nil {
+		return err
+	}
+	return w.confirm(sub, 1)
+}
+
+// dispatchKeyspaceEvent turns a keyspace notification into an Update message
+// whose ID is the notification channel (and so the changed key) and whose
+// Params is the event, e.g. "hset" or "del".
+func (w *Watcher) dispatchKeyspaceEvent(msg *rds.Message) {
+	data, err := w.encode(&MSG{Version: MSGVersion, Method: MethodUpdate, ID: msg.Channel, Params: msg.Payload})
+	if err != nil {
+		w.reportError(err)
+		return
+	}
+	w.dispatch(msg.Channel, string(data))
+}
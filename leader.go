@@ -0,0 +1,84 @@
+package rediswatcher
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	rds "github.com/redis/go-redis/v9"
+)
+
+const defaultLeaderTTL = 15 * time.Second
+
+// holdLock takes a lock that is free, or extends it if it is held with the
+// given token, and returns 1 if the token holds it.
+var holdLock = rds.NewScript(`
+local token = redis.call("GET", KEYS[1])
+if not token then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	return 1
+end
+if token == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// leaderKey is the lock held by the leader of the watchers on channel.
+func leaderKey(channel string) string {
+	return hashTag(channel) + ":leader"
+}
+
+// IsLeader reports whether the watcher currently holds the leadership, see
+// WatcherOptions.LeaderElection.
+func (w *Watcher) IsLeader() bool {
+	return atomic.LoadInt32(&w.leader) == 1
+}
+
+// setLeader records whether the watcher is the leader and calls
+// OnLeaderChange when it changed.
+func (w *Watcher) setLeader(leader bool) {
+	var v int32
+	if leader {
+		v = 1
+	}
+	if atomic.SwapInt32(&w.leader, v) != v && w.options.OnLeaderChange != nil {
+		w.options.OnLeaderChange(leader)
+	}
+}
+
+// elect takes the leadership whenever it is free and renews it while held,
+// until the watcher is closed, when it gives the leadership up.
+func (w *Watcher) elect() {
+	ticker := time.NewTicker(w.options.LeaderTTL / 3)
+	defer ticker.Stop()
+	key := leaderKey(w.options.Channel)
+	token := uuid.New().String()
+	for {
+		w.campaign(key, token)
+		select {
+		case <-w.close:
+			if w.IsLeader() {
+				// The watcher context is canceled by now.
+				if err := releaseLock.Run(context.Background(), w.pubClient, []string{key}, token).Err(); err != nil {
+					w.reportError(err)
+				}
+				w.setLeader(false)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// campaign takes or renews the leadership. The watcher steps down when the
+// lock cannot be renewed, as another watcher may take it once it expires.
+func (w *Watcher) campaign(key, token string) {
+	held, err := holdLock.Run(w.ctx, w.pubClient, []string{key}, token, w.options.LeaderTTL.Milliseconds()).Int()
+	if err != nil && !w.closed() {
+		w.reportError(err)
+	}
+	w.setLeader(err == nil && held == 1)
+}
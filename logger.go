@@ -0,0 +1,31 @@
+package rediswatcher
+
+import "log"
+
+// Logger receives the log output of a watcher. Its methods match those of
+// zap.SugaredLogger and logrus.Logger, so both can be used directly, and other
+// loggers such as slog only need a small adapter.
+type Logger interface {
+	Debug(args ...interface{})
+	Info(args ...interface{})
+	Warn(args ...interface{})
+	Error(args ...interface{})
+}
+
+// StdLogger writes to the standard logger of package log. It is the default
+// Logger and discards debug output.
+type StdLogger struct{}
+
+func (StdLogger) Debug(args ...interface{}) {}
+
+func (StdLogger) Info(args ...interface{}) {
+	log.Println(args...)
+}
+
+func (StdLogger) Warn(args ...interface{}) {
+	log.Println(args...)
+}
+
+func (StdLogger) Error(args ...interface{}) {
+	log.Println(args...)
+}
@@ -0,0 +1,85 @@
+package rediswatcher
+
+import (
+	"context"
+	"fmt"
+)
+
+// PublishOption customizes a message published with Publish.
+type PublishOption func(m *MSG)
+
+// WithMetadata adds metadata to the message, see MSG.Metadata. Later
+// values override earlier ones for the same key.
+func WithMetadata(metadata map[string]string) PublishOption {
+	return func(m *MSG) {
+		if m.Metadata == nil {
+			m.Metadata = make(map[string]string, len(metadata))
+		}
+		for k, v := range metadata {
+			m.Metadata[k] = v
+		}
+	}
+}
+
+// metadataKey is the context key of the metadata of the message handled.
+type metadataKey struct{}
+
+// contextWithMetadata returns ctx carrying metadata, see
+// MetadataFromContext.
+func contextWithMetadata(ctx context.Context, metadata map[string]string) context.Context {
+	if len(metadata) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, metadataKey{}, metadata)
+}
+
+// MetadataFromContext returns the metadata of the message handled with ctx,
+// the context passed to the typed handlers, see MSG.Metadata. It returns
+// nil when the message carries none.
+func MetadataFromContext(ctx context.Context) map[string]string {
+	metadata, _ := ctx.Value(metadataKey{}).(map[string]string)
+	return metadata
+}
+
+// addMetadata adds the metadata returned by WatcherOptions.Metadata for m
+// to m, keeping the values m already has for the same keys.
+func (w *Watcher) addMetadata(m *MSG) {
+	if w.options.Metadata == nil {
+		return
+	}
+	extra := w.options.Metadata(m)
+	if len(extra) == 0 {
+		return
+	}
+	metadata := make(map[string]string, len(m.Metadata)+len(extra))
+	for k, v := range extra {
+		metadata[k] = v
+	}
+	for k, v := range m.Metadata {
+		metadata[k] = v
+	}
+	m.Metadata = metadata
+}
+
+// Publish publishes m, an update message such as the ones of the Update
+// methods, e.g. MSG{Method: MethodUpdateForAddPolicy, Sec: "p", Ptype: "p",
+// Params: rule}, after applying opts to it. The message is checked with
+// MSG.Validate first; ID and Version are filled in by the watcher.
+func (w *Watcher) Publish(m MSG, opts ...PublishOption) error {
+	for _, opt := range opts {
+		opt(&m)
+	}
+	if m.Method == MethodPolicyHash {
+		return fmt.Errorf("%s messages are published by the watcher only", m.Method)
+	}
+	check := m
+	check.ID, check.Version = w.options.LocalID, MSGVersion
+	if err := check.Validate(); err != nil {
+		return err
+	}
+	return w.logRecord(func() error {
+		w.l.Lock()
+		defer w.l.Unlock()
+		return w.publish(m)
+	})
+}
@@ -0,0 +1,34 @@
+package rediswatcher
+
+import "time"
+
+// MetricsRecorder receives the activity of a watcher so that it can be
+// exported to a monitoring system such as Prometheus. Its methods are called
+// synchronously and must not block.
+type MetricsRecorder interface {
+	// Published is called for every message published, with the error
+	// returned by Redis if any.
+	Published(method string, err error)
+	// Received is called for every message read from Redis, before
+	// duplicates and messages of the watcher itself are filtered out.
+	// method is empty for payloads that are not messages, such as the
+	// "Close" notification of peers publishing WireVersion 1.
+	Received(method string)
+	// Handled is called with the time taken by the middlewares, handlers
+	// and update callback to process a message.
+	Handled(method string, d time.Duration)
+	// Reconnected is called when a lost subscription was re-created.
+	Reconnected()
+}
+
+// ThrottleRecorder is implemented by a MetricsRecorder that also counts the
+// messages rejected by the rate limiter, see WatcherOptions.PublishRate.
+type ThrottleRecorder interface {
+	Throttled(method string)
+}
+
+// ExpiryRecorder is implemented by a MetricsRecorder that also counts the
+// messages dropped for being too old, see WatcherOptions.MaxMessageAge.
+type ExpiryRecorder interface {
+	Expired(method string)
+}
@@ -0,0 +1,41 @@
+package rediswatcher
+
+import "context"
+
+// Delivery is a received message on its way to the callbacks.
+type Delivery struct {
+	// Channel is the channel the message arrived on.
+	Channel string
+	// Payload is the message as passed to the update callback.
+	Payload string
+	// MSG is the decoded message, or nil when Payload is not a message,
+	// e.g. the "Close" notification of a peer.
+	MSG *MSG
+	// Context is canceled once the watcher shut down or when the message
+	// was not handled within WatcherOptions.CallbackTimeout.
+	Context context.Context
+}
+
+// Handler processes a Delivery.
+type Handler func(d *Delivery)
+
+// Middleware wraps a Handler, e.g. to log, measure, filter or authorize
+// messages. It calls next to pass the delivery on, or returns without
+// calling it to drop the message.
+type Middleware func(next Handler) Handler
+
+// Use appends middlewares to the chain wrapping the invocation of the typed
+// handlers and update callbacks. The first middleware added is the
+// outermost. Messages handled by the watcher itself, such as policy hash
+// announcements, do not go through the chain.
+func (w *Watcher) Use(middlewares ...Middleware) {
+	// The chain is only used with dispatchL held.
+	w.dispatchL.Lock()
+	defer w.dispatchL.Unlock()
+	w.middlewares = append(w.middlewares, middlewares...)
+	chain := Handler(w.invoke)
+	for i := len(w.middlewares) - 1; i >= 0; i-- {
+		chain = w.middlewares[i](chain)
+	}
+	w.chain = chain
+}
@@ -0,0 +1,34 @@
+package rediswatcher
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Modes supported by WatcherOptions.Mode.
+const (
+	// ModeBoth publishes and subscribes, the default.
+	ModeBoth = "both"
+	// ModePubOnly only publishes: the watcher opens no subscription and
+	// runs none of the loops handling received messages.
+	ModePubOnly = "pub"
+	// ModeSubOnly only subscribes: the watcher opens no publishing client
+	// and its Update methods fail with ErrSubscribeOnly.
+	ModeSubOnly = "sub"
+)
+
+// ErrSubscribeOnly is returned by the Update methods of a watcher whose Mode
+// is ModeSubOnly.
+var ErrSubscribeOnly = errors.New("watcher is subscribe-only")
+
+// validateMode checks the mode setting and fills in the default.
+func validateMode(option *WatcherOptions) error {
+	switch option.Mode {
+	case "":
+		option.Mode = ModeBoth
+	case ModeBoth, ModePubOnly, ModeSubOnly:
+	default:
+		return fmt.Errorf("unsupported mode %q", option.Mode)
+	}
+	return nil
+}
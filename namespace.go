@@ -0,0 +1,34 @@
+package rediswatcher
+
+// applyNamespace prefixes the channels with WatcherOptions.Namespace. The
+// keys derived from the channel, such as the backlog and the presence
+// registry, are prefixed along.
+func applyNamespace(option *WatcherOptions) error {
+	if option.Namespace == "" {
+		return nil
+	}
+	namespace, err := normalizeChannel(option.Namespace)
+	if err != nil {
+		return err
+	}
+	option.Namespace = namespace
+	option.Channel = namespaced(namespace, option.Channel)
+	for i, channel := range option.Channels {
+		option.Channels[i] = namespaced(namespace, channel)
+	}
+	if option.ChannelPattern != "" {
+		option.ChannelPattern = namespaced(namespace, option.ChannelPattern)
+	}
+	if option.HeartbeatChannel != "" {
+		option.HeartbeatChannel = namespaced(namespace, option.HeartbeatChannel)
+	}
+	return nil
+}
+
+// namespaced returns channel in namespace, if any.
+func namespaced(namespace, channel string) string {
+	if namespace == "" {
+		return channel
+	}
+	return namespace + ":" + channel
+}
@@ -0,0 +1,34 @@
+package rediswatcher
+
+import (
+	"fmt"
+	"os"
+)
+
+// Origin describes the instance that published a message, see
+// WatcherOptions.IncludeOrigin.
+type Origin struct {
+	Hostname   string `json:"Hostname,omitempty"`
+	PID        int    `json:"PID,omitempty"`
+	AppVersion string `json:"AppVersion,omitempty"`
+}
+
+// String returns the origin as "hostname/pid", followed by the application
+// version if any.
+func (o *Origin) String() string {
+	s := fmt.Sprintf("%s/%d", o.Hostname, o.PID)
+	if o.AppVersion != "" {
+		s += " (" + o.AppVersion + ")"
+	}
+	return s
+}
+
+// newOrigin returns the origin stamped on the messages published with
+// option, or nil if IncludeOrigin is not set.
+func newOrigin(option WatcherOptions) *Origin {
+	if !option.IncludeOrigin {
+		return nil
+	}
+	hostname, _ := os.Hostname()
+	return &Origin{Hostname: hostname, PID: os.Getpid(), AppVersion: option.AppVersion}
+}
@@ -0,0 +1,43 @@
+package rediswatcher
+
+import "encoding/json"
+
+// Pause stops passing received messages to the middlewares, handlers and
+// update callbacks until Resume, e.g. while the application edits the
+// policy in bulk. Messages received meanwhile are dropped. Messages
+// already being handled are not interrupted.
+func (w *Watcher) Pause() {
+	w.dispatchL.Lock()
+	defer w.dispatchL.Unlock()
+	w.paused = true
+}
+
+// Resume resumes passing received messages to the callbacks after Pause.
+// With reload set, and if messages were dropped while paused, a single
+// Update message is passed to the callbacks first so that the policy is
+// reloaded once.
+func (w *Watcher) Resume(reload bool) {
+	w.dispatchL.Lock()
+	defer w.dispatchL.Unlock()
+	missed := w.missed
+	w.paused, w.missed = false, false
+	if !reload || !missed {
+		return
+	}
+	msg := &MSG{Version: MSGVersion, Method: MethodUpdate, ID: w.options.LocalID}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		w.reportError(err)
+		return
+	}
+	w.handleDelivery(&Delivery{Channel: w.options.Channel, Payload: string(data), MSG: msg})
+}
+
+// dropPaused reports whether a message is dropped because the watcher is
+// paused. It must be called with w.dispatchL held.
+func (w *Watcher) dropPaused() bool {
+	if w.paused {
+		w.missed = true
+	}
+	return w.paused
+}
@@ -0,0 +1,151 @@
+package rediswatcher
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	rds "github.com/redis/go-redis/v9"
+)
+
+// pollID is the ID of the Update messages delivered by poll, as the
+// publisher of a version is not known.
+const pollID = "poll"
+
+// defaultPollInterval is the PollInterval of the poll transport when not
+// set.
+const defaultPollInterval = time.Second
+
+// versionKey is the counter bumped by every message published on channel
+// when it is polled, see WatcherOptions.PollInterval.
+func versionKey(channel string) string {
+	return hashTag(channel) + ":version"
+}
+
+// validatePoll checks the poll settings and fills in defaults.
+func validatePoll(option *WatcherOptions) error {
+	if option.PollInterval < 0 {
+		return fmt.Errorf("invalid PollInterval %v", option.PollInterval)
+	}
+	if option.Transport != TransportPoll {
+		if option.PollTracking {
+			return errors.New("PollTracking requires the poll transport")
+		}
+		return nil
+	}
+	if len(option.Backends) > 0 || len(option.Channels) > 0 || option.ChannelPattern != "" ||
+		option.KeyspacePattern != "" || option.KeyspaceAdapter || option.ShardedPubSub || option.BacklogSize > 0 {
+		return errors.New("Backends, Channels, ChannelPattern, keyspace notifications, ShardedPubSub and BacklogSize are not supported with the poll transport")
+	}
+	if option.PollInterval == 0 {
+		option.PollInterval = defaultPollInterval
+	}
+	return nil
+}
+
+// versioned reports whether m, published on channel, bumps the version key
+// of the watcher channel when it is polled.
+func (w *Watcher) versioned(channel string, m *MSG) bool {
+	return w.options.PollInterval > 0 && channel == w.options.Channel && m.Method != MethodPolicyHash
+}
+
+// bumpVersion increments the version key of channel for m with the poll
+// transport, which publishes nothing else, and records the version as
+// published by the watcher, see poll. The other transports bump it in
+// publishNumbered.
+func (w *Watcher) bumpVersion(channel string, m *MSG) error {
+	if !w.versioned(channel, m) {
+		return nil
+	}
+	version, err := w.pubClient.Incr(w.ctx, versionKey(channel)).Result()
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt64(&w.ownVersion, version)
+	return nil
+}
+
+// poll reads the version key of the watcher channel every PollInterval and
+// delivers an Update message when it changed, with the poll transport, or
+// when fewer messages than versions were received on the channel
+// otherwise, e.g. while the subscription was down. As a message may be
+// received after its version was read, and the other way round, the
+// messages missing at one poll are only considered missed if they are
+// still missing at the next one.
+// With IgnoreSelf a single version bumped by the watcher itself is skipped.
+// With PollTracking the version is also read whenever trackVersion signals
+// pollNow.
+func (w *Watcher) poll() {
+	version, err := w.readVersion()
+	if err != nil {
+		w.reportError(err)
+	}
+	atomic.StoreInt64(&w.polledReceived, 0)
+	// owed is the number of versions whose messages were not received yet.
+	var owed int64
+
+	ticker := time.NewTicker(w.options.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.close:
+			return
+		case <-ticker.C:
+		case <-w.pollNow:
+		}
+		current, err := w.readVersion()
+		w.errL.Lock()
+		if w.options.Transport == TransportPoll {
+			w.streamErr = err
+		}
+		w.errL.Unlock()
+		if err != nil {
+			if !w.closed() {
+				w.reportError(err)
+			}
+			continue
+		}
+		received := atomic.SwapInt64(&w.polledReceived, 0)
+		var missed bool
+		if w.options.Transport == TransportPoll {
+			self := w.options.IgnoreSelf && current == version+1 && current == atomic.LoadInt64(&w.ownVersion)
+			missed = current != version && !self
+		} else {
+			owed -= received
+			missed = owed > 0 || current < version
+			owed += current - version
+			switch {
+			case missed:
+				owed = 0
+			case owed < -received:
+				// The messages received since the last poll may be
+				// followed by their versions, but the older ones are not.
+				owed = -received
+			}
+		}
+		version = current
+		if !missed {
+			continue
+		}
+		data, err := w.encode(&MSG{Version: w.options.WireVersion, Method: MethodUpdate, ID: pollID, Timestamp: time.Now().UnixMilli()})
+		if err != nil {
+			w.reportError(err)
+			continue
+		}
+		// Not counted as received, unlike the messages passed to dispatch.
+		w.dispatchL.Lock()
+		w.deliver(w.options.Channel, string(data))
+		w.dispatchL.Unlock()
+	}
+}
+
+// readVersion returns the version of the watcher channel, 0 if it was never
+// bumped.
+func (w *Watcher) readVersion() (int64, error) {
+	version, err := w.subClient.Get(w.ctx, versionKey(w.options.Channel)).Int64()
+	if err == rds.Nil {
+		return 0, nil
+	}
+	return version, err
+}
@@ -0,0 +1,186 @@
+package rediswatcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	rds "github.com/redis/go-redis/v9"
+)
+
+// ErrDuplicateLocalID is reported through the error callback when another
+// watcher registers in the presence registry with the same LocalID. Such
+// watchers drop each other's updates when IgnoreSelf is set.
+var ErrDuplicateLocalID = errors.New("another watcher uses the same LocalID")
+
+// Instance describes a watcher registered in the presence registry, see
+// WatcherOptions.PresenceInterval.
+type Instance struct {
+	ID       string    `json:"id"`
+	Hostname string    `json:"hostname"`
+	LastSeen time.Time `json:"lastSeen"`
+	// Session is random for every watcher, which tells apart the watchers
+	// misconfigured with the same ID.
+	Session string `json:"session,omitempty"`
+}
+
+// presenceKey is the sorted set of the instances registered on channel,
+// scored by the time they were last seen in milliseconds.
+func presenceKey(channel string) string {
+	return hashTag(channel) + ":instances"
+}
+
+// presenceInfoKey is the hash holding the Instance of every registered ID.
+func presenceInfoKey(channel string) string {
+	return hashTag(channel) + ":instances:info"
+}
+
+// defaultPresenceTTL is the PresenceTTL of watchers that do not register
+// themselves.
+const defaultPresenceTTL = 30 * time.Second
+
+// validatePresence defaults the presence TTL.
+func validatePresence(option *WatcherOptions) {
+	switch {
+	case option.PresenceInterval > 0 && option.PresenceTTL <= option.PresenceInterval:
+		option.PresenceTTL = 3 * option.PresenceInterval
+	case option.PresenceTTL <= 0:
+		option.PresenceTTL = defaultPresenceTTL
+	}
+}
+
+// presence periodically registers the watcher while its subscriptions are
+// up.
+func (w *Watcher) presence() {
+	ticker := time.NewTicker(w.options.PresenceInterval)
+	defer ticker.Stop()
+	hostname, _ := os.Hostname()
+	for {
+		if w.subscriptionErr() == nil {
+			if err := w.register(hostname); err != nil {
+				w.reportError(err)
+			}
+		}
+		select {
+		case <-w.close:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// register records the watcher as seen now, unless it was closed.
+func (w *Watcher) register(hostname string) error {
+	w.presenceL.Lock()
+	defer w.presenceL.Unlock()
+	if w.closed() {
+		return nil
+	}
+	if w.session == "" {
+		w.session = uuid.New().String()
+	}
+	key, infoKey := presenceKey(w.options.Channel), presenceInfoKey(w.options.Channel)
+	if err := w.checkLocalID(infoKey); err != nil {
+		w.reportError(err)
+	}
+	now := time.Now()
+	data, err := json.Marshal(Instance{ID: w.options.LocalID, Hostname: hostname, LastSeen: now, Session: w.session})
+	if err != nil {
+		return err
+	}
+	w.registered = now
+	_, err = w.pubClient.TxPipelined(w.ctx, func(pipe rds.Pipeliner) error {
+		pipe.ZAdd(w.ctx, key, rds.Z{Score: float64(now.UnixMilli()), Member: w.options.LocalID})
+		pipe.HSet(w.ctx, infoKey, w.options.LocalID, data)
+		pipe.PExpire(w.ctx, key, w.options.PresenceTTL)
+		pipe.PExpire(w.ctx, infoKey, w.options.PresenceTTL)
+		return nil
+	})
+	return err
+}
+
+// checkLocalID returns ErrDuplicateLocalID if the registry entry of the
+// watcher was written by another session since the watcher last registered.
+// The entry left by a previous run that crashed was written before the
+// first registration and is not reported. It must be called with
+// w.presenceL held.
+func (w *Watcher) checkLocalID(infoKey string) error {
+	if w.registered.IsZero() {
+		return nil
+	}
+	data, err := w.pubClient.HGet(w.ctx, infoKey, w.options.LocalID).Result()
+	if err == rds.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var instance Instance
+	if err := json.Unmarshal([]byte(data), &instance); err != nil {
+		return err
+	}
+	if instance.Session != "" && instance.Session != w.session && instance.LastSeen.After(w.registered) {
+		return fmt.Errorf("%w: %s is also registered by %s", ErrDuplicateLocalID, w.options.LocalID, instance.Hostname)
+	}
+	return nil
+}
+
+// unregister removes the watcher from the registry.
+func (w *Watcher) unregister() error {
+	_, err := w.pubClient.TxPipelined(w.ctx, func(pipe rds.Pipeliner) error {
+		pipe.ZRem(w.ctx, presenceKey(w.options.Channel), w.options.LocalID)
+		pipe.HDel(w.ctx, presenceInfoKey(w.options.Channel), w.options.LocalID)
+		return nil
+	})
+	return err
+}
+
+// Instances lists the watchers registered on the channel that were seen
+// within PresenceTTL, and removes the others from the registry. Only
+// watchers with a PresenceInterval register themselves, but any watcher on
+// the channel can list them.
+func (w *Watcher) Instances(ctx context.Context) ([]Instance, error) {
+	_, client := w.clients()
+	key, infoKey := presenceKey(w.options.Channel), presenceInfoKey(w.options.Channel)
+	cutoff := strconv.FormatInt(time.Now().Add(-w.options.PresenceTTL).UnixMilli(), 10)
+	expired, err := client.ZRangeByScore(ctx, key, &rds.ZRangeBy{Min: "-inf", Max: "(" + cutoff}).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(expired) > 0 {
+		_, err := client.TxPipelined(ctx, func(pipe rds.Pipeliner) error {
+			pipe.ZRemRangeByScore(ctx, key, "-inf", "("+cutoff)
+			pipe.HDel(ctx, infoKey, expired...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	ids, err := client.ZRangeByScore(ctx, key, &rds.ZRangeBy{Min: cutoff, Max: "+inf"}).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	values, err := client.HMGet(ctx, infoKey, ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+	instances := make([]Instance, 0, len(values))
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var instance Instance
+		if err := json.Unmarshal([]byte(data), &instance); err != nil {
+			return nil, err
+		}
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
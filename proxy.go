@@ -0,0 +1,227 @@
+package rediswatcher
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// dialFunc is the type of Options.Dialer.
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// defaultDialTimeout bounds connecting to and through a proxy when
+// Options.DialTimeout is not set, like go-redis does.
+const defaultDialTimeout = 5 * time.Second
+
+// applyProxy makes the clients connect through ProxyURL, if set. The proxy
+// itself is reached with Options.Dialer when set. As go-redis only applies
+// TLSConfig in its own dialer, the tunnel is wrapped in TLS here, verifying
+// the Redis host unless TLSConfig names another server.
+func applyProxy(option *WatcherOptions) error {
+	if option.ProxyURL == "" {
+		return nil
+	}
+	u, err := url.Parse(option.ProxyURL)
+	if err != nil {
+		return fmt.Errorf("invalid ProxyURL: %w", err)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid ProxyURL %q: no proxy address", option.ProxyURL)
+	}
+	timeout := option.DialTimeout
+	if timeout <= 0 {
+		timeout = defaultDialTimeout
+	}
+	forward := dialFunc(option.Dialer)
+	if forward == nil {
+		d := net.Dialer{Timeout: timeout}
+		forward = d.DialContext
+	}
+	var handshake func(conn net.Conn, u *url.URL, addr string) (net.Conn, error)
+	switch u.Scheme {
+	case "socks5", "socks5h":
+		handshake = socks5Connect
+	case "http":
+		handshake = httpConnect
+	default:
+		return fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	tlsConfig := option.TLSConfig
+	option.Dialer = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := forward(ctx, network, u.Host)
+		if err != nil {
+			return nil, err
+		}
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			deadline = time.Now().Add(timeout)
+		}
+		_ = conn.SetDeadline(deadline)
+		proxied, err := handshake(conn, u, addr)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("proxy %s: %w", u.Host, err)
+		}
+		if tlsConfig != nil {
+			config := tlsConfig.Clone()
+			if config.ServerName == "" {
+				config.ServerName, _, _ = net.SplitHostPort(addr)
+			}
+			secured := tls.Client(proxied, config)
+			if err := secured.HandshakeContext(ctx); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			proxied = secured
+		}
+		_ = conn.SetDeadline(time.Time{})
+		return proxied, nil
+	}
+	return nil
+}
+
+// socks5Connect asks the SOCKS5 proxy on conn to connect to addr, see RFC
+// 1928, authenticating with the user of u if any, see RFC 1929. The proxy
+// resolves host names.
+func socks5Connect(conn net.Conn, u *url.URL, addr string) (net.Conn, error) {
+	host, portString, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q", portString)
+	}
+
+	methods := []byte{0x00}
+	if u.User != nil {
+		methods = []byte{0x02}
+	}
+	if _, err := conn.Write(append([]byte{0x05, byte(len(methods))}, methods...)); err != nil {
+		return nil, err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, err
+	}
+	if reply[0] != 0x05 || reply[1] != methods[0] {
+		return nil, errors.New("no acceptable SOCKS5 authentication method")
+	}
+	if u.User != nil {
+		user := u.User.Username()
+		password, _ := u.User.Password()
+		if len(user) > 255 || len(password) > 255 {
+			return nil, errors.New("SOCKS5 credentials are too long")
+		}
+		auth := []byte{0x01, byte(len(user))}
+		auth = append(auth, user...)
+		auth = append(auth, byte(len(password)))
+		auth = append(auth, password...)
+		if _, err := conn.Write(auth); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return nil, err
+		}
+		if reply[1] != 0x00 {
+			return nil, errors.New("SOCKS5 authentication failed")
+		}
+	}
+
+	req := []byte{0x05, 0x01, 0x00}
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return nil, fmt.Errorf("host name %q is too long", host)
+		}
+		req = append(req, 0x03, byte(len(host)))
+		req = append(req, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		req = append(req, 0x01)
+		req = append(req, ip4...)
+	} else {
+		req = append(req, 0x04)
+		req = append(req, ip...)
+	}
+	req = append(req, byte(port>>8), byte(port))
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return nil, err
+	}
+	if head[1] != 0x00 {
+		return nil, fmt.Errorf("SOCKS5 connect to %s failed with code %d", addr, head[1])
+	}
+	// Skip the bound address and port.
+	var skip int
+	switch head[3] {
+	case 0x01:
+		skip = net.IPv4len + 2
+	case 0x04:
+		skip = net.IPv6len + 2
+	case 0x03:
+		n := make([]byte, 1)
+		if _, err := io.ReadFull(conn, n); err != nil {
+			return nil, err
+		}
+		skip = int(n[0]) + 2
+	default:
+		return nil, fmt.Errorf("unexpected SOCKS5 address type %d", head[3])
+	}
+	if _, err := io.ReadFull(conn, make([]byte, skip)); err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// httpConnect asks the HTTP proxy on conn to tunnel to addr with CONNECT,
+// authenticating with the user of u if any.
+func httpConnect(conn net.Conn, u *url.URL, addr string) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if u.User != nil {
+		password, _ := u.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(u.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("CONNECT to %s failed: %s", addr, resp.Status)
+	}
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a connection whose first bytes were read into r.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
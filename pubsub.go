@@ -0,0 +1,226 @@
+package rediswatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	rds "github.com/redis/go-redis/v9"
+)
+
+// PubSubClient publishes and subscribes through a Redis client other than
+// go-redis, set in WatcherOptions.PubSub. The rueidiswatcher package
+// implements it with a rueidis client.
+type PubSubClient interface {
+	// Ping checks the connection to Redis.
+	Ping(ctx context.Context) error
+	// Publish publishes message on channel, with SPUBLISH when sharded.
+	Publish(ctx context.Context, channel, message string, sharded bool) error
+	// Subscribe subscribes to channels, with SSUBSCRIBE when sharded, and
+	// to pattern unless it is empty. It returns once Redis confirmed every
+	// subscription, and keeps the messages received meanwhile for
+	// PubSubSubscription.Messages.
+	Subscribe(ctx context.Context, channels []string, pattern string, sharded bool) (PubSubSubscription, error)
+}
+
+// PubSubSubscription is a subscription opened by PubSubClient.Subscribe.
+type PubSubSubscription interface {
+	// Messages receives the messages of the subscription, in order.
+	Messages() <-chan *rds.Message
+	// Err receives the error that ended the subscription, e.g. when its
+	// connection failed.
+	Err() <-chan error
+	// Close closes the subscription.
+	Close() error
+}
+
+// ErrPubSubOnly is returned by the commands other than PING and PUBLISH
+// when the watcher publishes and subscribes through WatcherOptions.PubSub,
+// e.g. by Adapter, Epoch or Instances.
+var ErrPubSubOnly = errors.New("only pub/sub is supported with WatcherOptions.PubSub")
+
+// validatePubSub rejects the options that a PubSubClient cannot serve: the
+// ones building go-redis clients and the features keeping state in Redis.
+func validatePubSub(option *WatcherOptions) error {
+	if option.PubSub == nil {
+		return nil
+	}
+	for _, o := range []struct {
+		name string
+		set  bool
+	}{
+		{"SubClient", option.SubClient != nil},
+		{"PubClient", option.PubClient != nil},
+		{"ConfigurationEndpoint", option.ConfigurationEndpoint != ""},
+		{"ClusterAddrs", len(option.ClusterAddrs) > 0},
+		{"MasterName", option.MasterName != ""},
+		{"IAMAuth", option.IAMAuth != nil},
+		{"CredentialsRefreshInterval", option.CredentialsRefreshInterval > 0},
+		{"ProxyURL", option.ProxyURL != ""},
+		{"Discover", option.Discover != nil},
+		{"Backends", len(option.Backends) > 0},
+		{"Transport", option.Transport != "" && option.Transport != TransportPubSub},
+		{"PollInterval", option.PollInterval > 0},
+		{"KeyspacePattern", option.KeyspacePattern != ""},
+		{"KeyspaceAdapter", option.KeyspaceAdapter},
+		{"BacklogSize", option.BacklogSize > 0},
+		{"PolicyEpoch", option.PolicyEpoch},
+		{"LeaderElection", option.LeaderElection},
+		{"PresenceInterval", option.PresenceInterval > 0},
+		{"SnapshotSavePolicy", option.SnapshotSavePolicy},
+		{"ReloadConcurrency", option.ReloadConcurrency > 0},
+	} {
+		if o.set {
+			return fmt.Errorf("%s is not supported with WatcherOptions.PubSub", o.name)
+		}
+	}
+	return nil
+}
+
+// pubSubHook serves the commands of the go-redis client standing in for
+// the watcher clients with a PubSubClient: PING and PUBLISH go through it,
+// every other command and connection fails with ErrPubSubOnly.
+type pubSubHook struct {
+	ps PubSubClient
+}
+
+// newPubSubClient returns a go-redis client that never connects and
+// forwards its commands to ps, see pubSubHook.
+func newPubSubClient(ps PubSubClient) rds.UniversalClient {
+	client := rds.NewClient(&rds.Options{})
+	client.AddHook(pubSubHook{ps: ps})
+	return client
+}
+
+func (h pubSubHook) DialHook(rds.DialHook) rds.DialHook {
+	return func(context.Context, string, string) (net.Conn, error) {
+		return nil, ErrPubSubOnly
+	}
+}
+
+func (h pubSubHook) ProcessHook(rds.ProcessHook) rds.ProcessHook {
+	return func(ctx context.Context, cmd rds.Cmder) error {
+		err := h.process(ctx, cmd)
+		if err != nil {
+			cmd.SetErr(err)
+		}
+		return err
+	}
+}
+
+func (h pubSubHook) ProcessPipelineHook(rds.ProcessPipelineHook) rds.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []rds.Cmder) error {
+		for _, cmd := range cmds {
+			cmd.SetErr(ErrPubSubOnly)
+		}
+		return ErrPubSubOnly
+	}
+}
+
+func (h pubSubHook) process(ctx context.Context, cmd rds.Cmder) error {
+	args := cmd.Args()
+	switch name := strings.ToLower(cmd.Name()); {
+	case name == "ping":
+		if err := h.ps.Ping(ctx); err != nil {
+			return err
+		}
+		if c, ok := cmd.(*rds.StatusCmd); ok {
+			c.SetVal("PONG")
+		}
+		return nil
+	case (name == "publish" || name == "spublish") && len(args) == 3:
+		return h.ps.Publish(ctx, argString(args[1]), argString(args[2]), name == "spublish")
+	default:
+		return ErrPubSubOnly
+	}
+}
+
+// argString returns the string sent to Redis for the command argument arg.
+func argString(arg interface{}) string {
+	switch a := arg.(type) {
+	case string:
+		return a
+	case []byte:
+		return string(a)
+	default:
+		return fmt.Sprint(a)
+	}
+}
+
+// openPubSub subscribes through WatcherOptions.PubSub to the main channel,
+// the additional channels and the channel pattern.
+func (w *Watcher) openPubSub() (PubSubSubscription, error) {
+	channels := append([]string{w.options.Channel}, w.options.Channels...)
+	return w.options.PubSub.Subscribe(w.ctx, channels, w.options.ChannelPattern, w.options.ShardedPubSub)
+}
+
+// subscribePubSub subscribes through WatcherOptions.PubSub and delivers the
+// messages until the watcher is closed. When the subscription ends it is
+// re-created with backoff, like resubscribe does for the go-redis clients.
+func (w *Watcher) subscribePubSub() error {
+	sub, err := w.openPubSub()
+	if err != nil {
+		return err
+	}
+	w.start(func() {
+		for {
+			var err error
+			select {
+			case <-w.close:
+				_ = sub.Close()
+				return
+			case msg := <-sub.Messages():
+				w.receive(msg)
+				continue
+			case err = <-sub.Err():
+			}
+			_ = sub.Close()
+			if err == nil {
+				err = errors.New("pub/sub subscription closed")
+			}
+			w.errL.Lock()
+			w.streamErr = err
+			w.errL.Unlock()
+			w.reportError(err)
+			if sub = w.resubscribePubSub(); sub == nil {
+				return
+			}
+		}
+	})
+	return nil
+}
+
+// resubscribePubSub re-creates the subscription through
+// WatcherOptions.PubSub with exponential backoff and jitter until it
+// succeeds, or returns nil once the watcher is closed.
+func (w *Watcher) resubscribePubSub() PubSubSubscription {
+	backoff := w.options.ReconnectMinBackoff
+	for {
+		select {
+		case <-w.close:
+			return nil
+		case <-time.After(jitter(backoff)):
+		}
+		sub, err := w.openPubSub()
+		if err == nil {
+			w.errL.Lock()
+			w.streamErr = nil
+			w.errL.Unlock()
+			w.logger().Info("resubscribed to " + w.options.Channel)
+			if w.options.Metrics != nil {
+				w.options.Metrics.Reconnected()
+			}
+			if w.options.OnReconnect != nil {
+				w.options.OnReconnect()
+			}
+			return sub
+		}
+		w.reportError(err)
+		if backoff *= 2; backoff > w.options.ReconnectMaxBackoff {
+			backoff = w.options.ReconnectMaxBackoff
+		}
+	}
+}
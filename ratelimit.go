@@ -0,0 +1,63 @@
+package rediswatcher
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned by the Update methods when a message exceeds
+// WatcherOptions.PublishRate.
+var ErrRateLimited = errors.New("publish rate limit exceeded")
+
+// tokenBucket allows rate events per second on average with bursts of up to
+// burst events.
+type tokenBucket struct {
+	l      sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// setRateDefaults defaults PublishBurst to the rate rounded up.
+func setRateDefaults(option *WatcherOptions) {
+	if option.PublishRate > 0 && option.PublishBurst <= 0 {
+		option.PublishBurst = int(math.Ceil(option.PublishRate))
+	}
+}
+
+func newTokenBucket(option WatcherOptions) *tokenBucket {
+	if option.PublishRate <= 0 {
+		return nil
+	}
+	burst := float64(option.PublishBurst)
+	return &tokenBucket{rate: option.PublishRate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// allow takes a token if one is available.
+func (b *tokenBucket) allow() bool {
+	b.l.Lock()
+	defer b.l.Unlock()
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// throttle reports whether m exceeds the publish rate, recording it in
+// Metrics if so.
+func (w *Watcher) throttle(m MSG) bool {
+	if w.limiter == nil || w.limiter.allow() {
+		return false
+	}
+	if r, ok := w.options.Metrics.(ThrottleRecorder); ok {
+		r.Throttled(m.Method)
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package rediswatcher
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// PolicyHash returns a digest of the "p" and "g" policies of m. It does not
+// depend on the order of the rules, so two models holding the same policy
+// hash identically. Each rule is hashed as its JSON encoding, so that rules
+// whose fields only differ in where they are split, e.g. ["a, b"] and
+// ["a", "b"], hash differently.
+func PolicyHash(m model.Model) string {
+	h := sha256.New()
+	for _, sec := range []string{"p", "g"} {
+		ptypes := make([]string, 0, len(m[sec]))
+		for ptype := range m[sec] {
+			ptypes = append(ptypes, ptype)
+		}
+		sort.Strings(ptypes)
+		for _, ptype := range ptypes {
+			rules := make([]string, 0, len(m[sec][ptype].Policy))
+			for _, rule := range m[sec][ptype].Policy {
+				// Marshaling a []string cannot fail.
+				data, _ := json.Marshal(rule)
+				rules = append(rules, string(data))
+			}
+			sort.Strings(rules)
+			h.Write([]byte(sec + "." + ptype + "\n"))
+			for _, rule := range rules {
+				h.Write([]byte(rule + "\n"))
+			}
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// reconcile periodically publishes the hash of the local policy so peers can
+// detect drift caused by missed incremental updates. With LeaderElection
+// only the leader publishes it.
+func (w *Watcher) reconcile() {
+	ticker := time.NewTicker(w.options.ReconcileInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.close:
+			return
+		case <-ticker.C:
+			if w.options.LeaderElection && !w.IsLeader() {
+				continue
+			}
+			_ = w.logRecord(func() error {
+				w.l.Lock()
+				defer w.l.Unlock()
+				return w.publish(MSG{Method: MethodPolicyHash, Params: PolicyHash(w.options.PolicyModel())})
+			})
+		}
+	}
+}
+
+// handlePolicyHash compares the hash announced by a peer with the local one
+// and triggers OnReconcile when they differ, so both sides of a mismatch
+// reload. Reloads are limited to one per ReconcileInterval, and to one at a
+// time, so a burst of announcements does not cause a reload storm. They run
+// on their own goroutine so that the messages received meanwhile are not
+// held back.
+func (w *Watcher) handlePolicyHash(msg *MSG) {
+	if msg.ID == w.options.LocalID || w.options.PolicyModel == nil || w.options.OnReconcile == nil {
+		return
+	}
+	hash, ok := msg.Params.(string)
+	if !ok {
+		w.reportError(fmt.Errorf("unexpected params type %T for %s message", msg.Params, msg.Method))
+		return
+	}
+	local := PolicyHash(w.options.PolicyModel())
+	if hash == local {
+		return
+	}
+	w.l.Lock()
+	if w.reconciling || time.Since(w.lastReconcile) < w.options.ReconcileInterval {
+		w.l.Unlock()
+		return
+	}
+	w.lastReconcile = time.Now()
+	w.reconciling = true
+	// Announce the local hash before reloading so the peer detects the
+	// mismatch as well, even if this node was the divergent one and its
+	// reload makes both hashes equal.
+	err := w.publish(MSG{Method: MethodPolicyHash, Params: local})
+	w.l.Unlock()
+	if err != nil {
+		w.reportError(err)
+	}
+	w.start(func() {
+		defer func() {
+			w.l.Lock()
+			w.reconciling = false
+			w.l.Unlock()
+		}()
+		defer w.recoverCallback(w.options.Channel)
+		w.options.OnReconcile()
+	})
+}
@@ -0,0 +1,198 @@
+package rediswatcher
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	rds "github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultReconnectMinBackoff = 100 * time.Millisecond
+	defaultReconnectMaxBackoff = 10 * time.Second
+	// healthCheckInterval is how long a subscription may stay silent before
+	// it is pinged. A subscription that does not answer within another
+	// interval is considered dead and re-created.
+	healthCheckInterval = 30 * time.Second
+)
+
+// subscription holds the current PubSub of a subscriber loop, which is
+// replaced every time the loop reconnects, and the error that made it
+// reconnect until it succeeded.
+type subscription struct {
+	l      sync.Mutex
+	sub    *rds.PubSub
+	err    error
+	closed bool
+	// reopen is set by reset until the loop re-created the PubSub.
+	reopen bool
+}
+
+func (s *subscription) get() *rds.PubSub {
+	s.l.Lock()
+	defer s.l.Unlock()
+	return s.sub
+}
+
+// set replaces the current PubSub and reports whether the subscription is
+// still open. A PubSub set after close is closed right away.
+func (s *subscription) set(sub *rds.PubSub) bool {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if s.closed {
+		_ = sub.Close()
+		return false
+	}
+	s.sub = sub
+	s.err = nil
+	s.reopen = false
+	return true
+}
+
+// reset closes the current PubSub so that the loop receiving on it
+// re-creates it, e.g. on the new master after a failover.
+func (s *subscription) reset() {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if !s.closed {
+		s.reopen = true
+		_ = s.sub.Close()
+	}
+}
+
+// resetting reports whether the PubSub was closed by reset.
+func (s *subscription) resetting() bool {
+	s.l.Lock()
+	defer s.l.Unlock()
+	return s.reopen
+}
+
+// fail records that the subscription failed with err.
+func (s *subscription) fail(err error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.err = err
+}
+
+// lastErr returns the error of a subscription that has not recovered yet.
+func (s *subscription) lastErr() error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	return s.err
+}
+
+// close closes the current PubSub, which interrupts a blocked receive.
+func (s *subscription) close() {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if !s.closed {
+		s.closed = true
+		_ = s.sub.Close()
+	}
+}
+
+// track registers sub so that Close can interrupt the loop receiving on it.
+// It must be called with w.l held.
+func (w *Watcher) track(sub *rds.PubSub) *subscription {
+	s := &subscription{sub: sub}
+	w.subs = append(w.subs, s)
+	return s
+}
+
+// closed reports whether Close was called.
+func (w *Watcher) closed() bool {
+	select {
+	case <-w.close:
+		return true
+	default:
+		return false
+	}
+}
+
+// listen delivers the messages of s until the watcher is closed. When the
+// connection fails the subscription is re-created on client, see
+// resubscribe.
+func (w *Watcher) listen(client rds.UniversalClient, s *subscription) {
+	defer s.close()
+	pinged := false
+	for {
+		msg, err := s.get().ReceiveTimeout(w.ctx, healthCheckInterval)
+		if w.closed() {
+			return
+		}
+		if err == nil {
+			pinged = false
+			switch m := msg.(type) {
+			case *rds.Message:
+				w.receive(m)
+			case *rds.Subscription:
+				if m.Kind == "subscribe" {
+					w.tenantSubscribed(m.Channel)
+				}
+			}
+			continue
+		}
+		var netErr net.Error
+		if !pinged && errors.As(err, &netErr) && netErr.Timeout() {
+			pinged = true
+			if err = s.get().Ping(w.ctx); err == nil {
+				continue
+			}
+		}
+		if !s.resetting() {
+			w.reportError(err)
+			s.fail(err)
+		}
+		pinged = false
+		if !w.resubscribe(client, s) {
+			return
+		}
+	}
+}
+
+// resubscribe re-creates the subscription of s with exponential backoff and
+// jitter until it succeeds or the watcher is closed, and reports whether it
+// succeeded.
+func (w *Watcher) resubscribe(client rds.UniversalClient, s *subscription) bool {
+	_ = s.get().Close()
+	backoff := w.options.ReconnectMinBackoff
+	for {
+		// Jitter keeps a fleet of watchers from reconnecting in lockstep
+		// after a Redis restart.
+		select {
+		case <-w.close:
+			return false
+		case <-time.After(jitter(backoff)):
+		}
+		sub, err := w.openSubscription(client)
+		if err == nil {
+			if !s.set(sub) {
+				return false
+			}
+			w.logger().Info("resubscribed to " + w.options.Channel)
+			if w.options.Metrics != nil {
+				w.options.Metrics.Reconnected()
+			}
+			if w.options.BacklogSize > 0 {
+				w.catchUp()
+			}
+			if w.options.OnReconnect != nil {
+				w.options.OnReconnect()
+			}
+			return true
+		}
+		_ = sub.Close()
+		w.reportError(err)
+		if backoff *= 2; backoff > w.options.ReconnectMaxBackoff {
+			backoff = w.options.ReconnectMaxBackoff
+		}
+	}
+}
+
+// jitter returns a random duration between half of backoff and backoff.
+func jitter(backoff time.Duration) time.Duration {
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
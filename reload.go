@@ -0,0 +1,121 @@
+package rediswatcher
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	rds "github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultReloadLockTTL = 30 * time.Second
+	// reloadPollInterval is how often a watcher waiting for a reload slot
+	// tries to take one.
+	reloadPollInterval = 100 * time.Millisecond
+)
+
+// releaseLock deletes a lock, such as a reload slot, only if it is still
+// held with the token it was taken with, so a lock that expired and was
+// taken by another watcher is not released.
+var releaseLock = rds.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// reloadKey is the key of the reload slot i of channel.
+func reloadKey(channel string, i int) string {
+	return hashTag(channel) + ":reload:" + strconv.Itoa(i)
+}
+
+// reloads reports whether d is a message that usually makes the receivers
+// reload their whole policy.
+func reloads(d *Delivery) bool {
+	return d.MSG != nil && (d.MSG.Method == MethodUpdate || d.MSG.Method == MethodUpdateForSavePolicy)
+}
+
+// holdReload delays a reload message by a random duration of up to
+// ReloadJitter, and reports whether it held d back. The messages received
+// meanwhile are held back behind it to keep their order, and a newer reload
+// message replaces it along with them, as it supersedes them. It must be
+// called with w.dispatchL held.
+func (w *Watcher) holdReload(d *Delivery) bool {
+	if w.options.ReloadJitter <= 0 {
+		return false
+	}
+	if !reloads(d) {
+		if w.reloadTimer == nil {
+			return false
+		}
+		w.heldAfter = append(w.heldAfter, d)
+		return true
+	}
+	w.heldReload, w.heldAfter = d, nil
+	if w.reloadTimer == nil {
+		// The timer counts as a loop so that Shutdown does not stop the
+		// callback workers under it.
+		w.loops.Add(1)
+		w.reloadTimer = time.AfterFunc(time.Duration(rand.Int63n(int64(w.options.ReloadJitter))), func() {
+			defer w.loops.Done()
+			w.dispatchL.Lock()
+			defer w.dispatchL.Unlock()
+			held := append([]*Delivery{w.heldReload}, w.heldAfter...)
+			w.reloadTimer, w.heldReload, w.heldAfter = nil, nil, nil
+			if w.closed() {
+				return
+			}
+			for _, d := range held {
+				w.handleDelivery(d)
+			}
+		})
+	}
+	return true
+}
+
+// dropHeldReload drops the messages held back by holdReload when the
+// watcher is closed or restarted.
+func (w *Watcher) dropHeldReload() {
+	w.dispatchL.Lock()
+	defer w.dispatchL.Unlock()
+	if w.reloadTimer != nil && w.reloadTimer.Stop() {
+		w.loops.Done()
+		w.reloadTimer, w.heldReload, w.heldAfter = nil, nil, nil
+	}
+}
+
+// acquireReload waits until it holds one of the reload slots, and returns
+// the function releasing it. It gives up when ctx is done.
+func (w *Watcher) acquireReload(ctx context.Context) (func(), error) {
+	_, client := w.clients()
+	channel := w.options.Channel
+	token := uuid.New().String()
+	for {
+		// Starting at a random slot spreads the watchers over the slots.
+		offset := rand.Intn(w.options.ReloadConcurrency)
+		for i := 0; i < w.options.ReloadConcurrency; i++ {
+			key := reloadKey(channel, (offset+i)%w.options.ReloadConcurrency)
+			ok, err := client.SetNX(ctx, key, token, w.options.ReloadLockTTL).Result()
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return func() {
+					// The watcher context may be canceled by Shutdown while
+					// the reload runs.
+					if err := releaseLock.Run(context.Background(), client, []string{key}, token).Err(); err != nil {
+						w.reportError(err)
+					}
+				}, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(jitter(reloadPollInterval)):
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package rediswatcher
+
+import (
+	"context"
+
+	rds "github.com/redis/go-redis/v9"
+)
+
+// Restart tears down the subscriptions and Redis clients of the watcher and
+// re-establishes them with the same options, e.g. after a failure the
+// automatic reconnection cannot recover from. Callbacks, handlers and
+// middlewares are preserved. Like Shutdown it first publishes the messages
+// held back or queued and waits for the callbacks in progress, so it must
+// not be called from a callback. Clients passed in SubClient and PubClient
+// are reused rather than re-created.
+//
+// With a backlog the messages published during the restart are replayed;
+// otherwise they are missed, as with any reconnection.
+func (w *Watcher) Restart(ctx context.Context) error {
+	return w.restart(ctx, true, nil)
+}
+
+// restart stops the subscriptions and background loops, re-creates the
+// Redis clients if asked to, calls reconfigure with w.l held and starts
+// everything again.
+func (w *Watcher) restart(ctx context.Context, newClients bool, reconfigure func() error) error {
+	w.restartL.Lock()
+	defer w.restartL.Unlock()
+	if w.closed() {
+		return ErrClosed
+	}
+
+	w.l.Lock()
+	if err := w.flushPending(); err != nil {
+		w.reportError(err)
+	}
+	w.drainQueue()
+	w.presenceL.Lock()
+	close(w.close)
+	w.presenceL.Unlock()
+	for _, s := range w.subs {
+		s.close()
+	}
+	w.subs = nil
+	w.l.Unlock()
+	w.cancel()
+	w.dropHeldReload()
+	w.loops.Wait()
+	w.stopWorkers()
+	w.workers.Wait()
+
+	if newClients {
+		for _, client := range w.ownedClients() {
+			if err := client.Close(); err != nil {
+				w.reportError(err)
+			}
+		}
+	}
+
+	w.l.Lock()
+	w.close = make(chan struct{})
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	if newClients {
+		w.initClients(w.options)
+		w.backends = newBackends(w.options)
+	}
+	var err error
+	if reconfigure != nil {
+		err = reconfigure()
+	}
+	w.startQueue()
+	w.startSelfApply()
+	w.startDiscovery()
+	w.startCredentialsRefresh()
+	w.l.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if w.subClient != nil {
+		if err := w.subClient.Ping(ctx).Err(); err != nil {
+			return err
+		}
+	}
+	if w.pubClient != w.subClient {
+		if err := w.pubClient.Ping(ctx).Err(); err != nil {
+			return err
+		}
+	}
+	if w.subClient == nil {
+		return nil
+	}
+	if err := w.listenAll(); err != nil {
+		return err
+	}
+	if w.options.BacklogSize > 0 {
+		w.catchUp()
+	}
+	return nil
+}
+
+// ownedClients returns the Redis clients created by the watcher, as opposed
+// to the ones passed in the options.
+func (w *Watcher) ownedClients() []rds.UniversalClient {
+	var clients []rds.UniversalClient
+	if w.subClient != nil && w.options.SubClient == nil {
+		clients = append(clients, w.subClient)
+	}
+	if w.options.PubClient == nil && w.pubClient != w.subClient {
+		clients = append(clients, w.pubClient)
+	}
+	for _, client := range w.backends {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// clients returns the current Redis clients, which Restart replaces.
+func (w *Watcher) clients() (sub, pub rds.UniversalClient) {
+	w.l.Lock()
+	defer w.l.Unlock()
+	return w.subClient, w.pubClient
+}
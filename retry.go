@@ -0,0 +1,32 @@
+package rediswatcher
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	defaultPublishMinBackoff = 50 * time.Millisecond
+	defaultPublishMaxBackoff = 2 * time.Second
+)
+
+// publishRetrying publishes m through publishGuarded, retrying failures with
+// exponential backoff and jitter up to PublishMaxAttempts times in total.
+func (w *Watcher) publishRetrying(m MSG) error {
+	backoff := w.options.PublishMinBackoff
+	for attempt := 1; ; attempt++ {
+		err := w.publishGuarded(m)
+		if err == nil || err == ErrCircuitOpen || attempt >= w.options.PublishMaxAttempts {
+			return err
+		}
+		w.logger().Warn(fmt.Sprintf("retrying %s after attempt %d failed: %v", m.Method, attempt, err))
+		select {
+		case <-w.close:
+			return err
+		case <-time.After(jitter(backoff)):
+		}
+		if backoff *= 2; backoff > w.options.PublishMaxBackoff {
+			backoff = w.options.PublishMaxBackoff
+		}
+	}
+}
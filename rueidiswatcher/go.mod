@@ -0,0 +1,19 @@
+module github.com/casbin/redis-watcher/v2/rueidiswatcher
+
+go 1.20
+
+require (
+	github.com/casbin/casbin/v2 v2.30.0
+	github.com/casbin/redis-watcher/v2 v2.0.0
+	github.com/redis/go-redis/v9 v9.7.3
+	github.com/redis/rueidis v1.0.19
+)
+
+require (
+	github.com/Knetic/govaluate v3.0.1-0.20171022003610-9aa49832a739+incompatible // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/google/uuid v1.2.0 // indirect
+)
+
+replace github.com/casbin/redis-watcher/v2 => ../
@@ -0,0 +1,144 @@
+// Package rueidiswatcher publishes and subscribes the messages of a
+// rediswatcher.Watcher through a rueidis client, for applications
+// standardized on rueidis. It is a separate module as rueidis requires a
+// newer Go version than the watcher.
+package rueidiswatcher
+
+import (
+	"context"
+	"sync"
+
+	"github.com/casbin/casbin/v2/persist"
+	rediswatcher "github.com/casbin/redis-watcher/v2"
+	rds "github.com/redis/go-redis/v9"
+	"github.com/redis/rueidis"
+)
+
+// NewWatcher creates a watcher publishing and subscribing through client,
+// see New. The watcher does not close client.
+func NewWatcher(client rueidis.Client, option rediswatcher.WatcherOptions) (persist.Watcher, error) {
+	option.PubSub = New(client)
+	return rediswatcher.NewWatcher("", option)
+}
+
+// New returns the PubSubClient publishing with client and subscribing on
+// connections dedicated from it, to be set in WatcherOptions.PubSub.
+func New(client rueidis.Client) rediswatcher.PubSubClient {
+	return &pubSub{client: client}
+}
+
+type pubSub struct {
+	client rueidis.Client
+}
+
+func (p *pubSub) Ping(ctx context.Context) error {
+	return p.client.Do(ctx, p.client.B().Ping().Build()).Error()
+}
+
+func (p *pubSub) Publish(ctx context.Context, channel, message string, sharded bool) error {
+	if sharded {
+		return p.client.Do(ctx, p.client.B().Spublish().Channel(channel).Message(message).Build()).Error()
+	}
+	return p.client.Do(ctx, p.client.B().Publish().Channel(channel).Message(message).Build()).Error()
+}
+
+// Subscribe subscribes a connection dedicated from the client. The hook of
+// the connection runs on the goroutine reading it, so the messages are
+// queued without bound rather than blocking it while the subscriptions are
+// confirmed.
+func (p *pubSub) Subscribe(ctx context.Context, channels []string, pattern string, sharded bool) (rediswatcher.PubSubSubscription, error) {
+	client, _ := p.client.Dedicate()
+	s := &subscription{
+		client: client,
+		msgs:   make(chan *rds.Message),
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	s.errs = client.SetPubSubHooks(rueidis.PubSubHooks{
+		OnMessage: func(m rueidis.PubSubMessage) {
+			s.push(&rds.Message{Channel: m.Channel, Pattern: m.Pattern, Payload: m.Message})
+		},
+	})
+	go s.forward()
+
+	cmds := []rueidis.Completed{client.B().Subscribe().Channel(channels...).Build()}
+	if sharded {
+		cmds[0] = client.B().Ssubscribe().Channel(channels...).Build()
+	}
+	if pattern != "" {
+		cmds = append(cmds, client.B().Psubscribe().Pattern(pattern).Build())
+	}
+	for _, cmd := range cmds {
+		if err := client.Do(ctx, cmd).Error(); err != nil {
+			_ = s.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// subscription is a connection dedicated from the rueidis client and
+// subscribed by Subscribe.
+type subscription struct {
+	client rueidis.DedicatedClient
+	errs   <-chan error
+
+	l     sync.Mutex
+	queue []*rds.Message
+	// wake is signaled when a message is queued.
+	wake chan struct{}
+	msgs chan *rds.Message
+	done chan struct{}
+	once sync.Once
+}
+
+// push queues msg for Messages.
+func (s *subscription) push(msg *rds.Message) {
+	s.l.Lock()
+	s.queue = append(s.queue, msg)
+	s.l.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// forward passes the queued messages to msgs, in order, until s is closed.
+func (s *subscription) forward() {
+	for {
+		s.l.Lock()
+		queue := s.queue
+		s.queue = nil
+		s.l.Unlock()
+		for _, msg := range queue {
+			select {
+			case s.msgs <- msg:
+			case <-s.done:
+				return
+			}
+		}
+		select {
+		case <-s.wake:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *subscription) Messages() <-chan *rds.Message {
+	return s.msgs
+}
+
+func (s *subscription) Err() <-chan error {
+	return s.errs
+}
+
+// Close closes the dedicated connection, which discards it rather than
+// returning it subscribed to the pool.
+func (s *subscription) Close() error {
+	s.once.Do(func() {
+		close(s.done)
+		s.client.Close()
+	})
+	return nil
+}
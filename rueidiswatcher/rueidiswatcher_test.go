@@ -0,0 +1,81 @@
+package rueidiswatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	rediswatcher "github.com/casbin/redis-watcher/v2"
+	"github.com/redis/rueidis"
+)
+
+func TestWatcher(t *testing.T) {
+	client, err := rueidis.NewClient(rueidis.ClientOption{InitAddress: []string{"127.0.0.1:6379"}})
+	if err != nil {
+		t.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	defer client.Close()
+	if _, err := NewWatcher(client, rediswatcher.WatcherOptions{BacklogSize: 10}); err == nil {
+		t.Fatalf("BacklogSize should be rejected with the rueidis client")
+	}
+
+	channel := fmt.Sprintf("/casbin/rueidis/%d", time.Now().UnixNano())
+	w, err := NewWatcher(client, rediswatcher.WatcherOptions{Channel: channel})
+	if err != nil {
+		t.Fatalf("Failed to create the watcher: %v", err)
+	}
+	received := make(chan string, 10)
+	_ = w.SetUpdateCallback(func(msg string) {
+		received <- msg
+	})
+
+	// Messages flow both ways between rueidis and go-redis watchers.
+	w2, err := rediswatcher.NewWatcher("127.0.0.1:6379", rediswatcher.WatcherOptions{Channel: channel})
+	if err != nil {
+		t.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	received2 := make(chan string, 10)
+	_ = w2.SetUpdateCallback(func(msg string) {
+		received2 <- msg
+	})
+	if err := w2.Update(); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	select {
+	case msg := <-received:
+		if !strings.Contains(msg, rediswatcher.MethodUpdate) {
+			t.Fatalf("the rueidis watcher should receive an Update instead of %s", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("the rueidis watcher should receive the message published with go-redis")
+	}
+	if err := w.Update(); err != nil {
+		t.Fatalf("Failed to publish through rueidis: %v", err)
+	}
+	select {
+	case msg := <-received2:
+		if !strings.Contains(msg, rediswatcher.MethodUpdate) {
+			t.Fatalf("the go-redis watcher should receive an Update instead of %s", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("the go-redis watcher should receive the message published through rueidis")
+	}
+
+	if err := w.(*rediswatcher.Watcher).Ping(context.Background()); err != nil {
+		t.Fatalf("the rueidis watcher should be healthy: %v", err)
+	}
+	if err := w.(*rediswatcher.Watcher).Adapter().LoadPolicy(model.Model{}); !errors.Is(err, rediswatcher.ErrPubSubOnly) {
+		t.Fatalf("Adapter should fail with ErrPubSubOnly instead of %v", err)
+	}
+	w.Close()
+	w2.Close()
+	// The watcher does not close the client it was given.
+	if err := client.Do(context.Background(), client.B().Ping().Build()).Error(); err != nil {
+		t.Fatalf("the rueidis client should stay open: %v", err)
+	}
+	time.Sleep(time.Millisecond * 500)
+}
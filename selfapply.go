@@ -0,0 +1,68 @@
+package rediswatcher
+
+import "encoding/json"
+
+// selfApplyQueueSize bounds the published messages waiting to be passed to
+// the callbacks of the watcher, see WatcherOptions.SelfApply.
+const selfApplyQueueSize = 100
+
+// startSelfApply starts the loop passing the messages published by the
+// watcher to its own callbacks when SelfApply is set.
+func (w *Watcher) startSelfApply() {
+	if !w.options.SelfApply {
+		return
+	}
+	if w.selfApplied == nil {
+		w.selfApplied = make(chan MSG, selfApplyQueueSize)
+	}
+	closed := w.close
+	w.start(func() {
+		for {
+			select {
+			case m := <-w.selfApplied:
+				w.applySelf(m)
+			case <-closed:
+				// Apply the messages published while closing.
+				for {
+					select {
+					case m := <-w.selfApplied:
+						w.applySelf(m)
+					default:
+						return
+					}
+				}
+			}
+		}
+	})
+}
+
+// selfApply queues the published message m for the callbacks of the
+// watcher. They are called on another goroutine, as the Update methods
+// hold w.l.
+func (w *Watcher) selfApply(m MSG) {
+	m.Version, m.ID = MSGVersion, w.options.LocalID
+	select {
+	case w.selfApplied <- m:
+	case <-w.close:
+	}
+}
+
+// applySelf passes m to the callbacks like a received message, unless it
+// is filtered out or the watcher is paused.
+func (w *Watcher) applySelf(m MSG) {
+	channel := w.options.Channel
+	if m.channel != "" {
+		channel = m.channel
+	}
+	data, err := json.Marshal(&m)
+	if err != nil {
+		w.reportError(err)
+		return
+	}
+	w.dispatchL.Lock()
+	defer w.dispatchL.Unlock()
+	if w.filtered(&m) || w.dropPaused() {
+		return
+	}
+	w.handleDelivery(&Delivery{Channel: channel, Payload: string(data), MSG: &m})
+}
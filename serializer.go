@@ -0,0 +1,49 @@
+package rediswatcher
+
+import "encoding/json"
+
+// Serializer encodes messages for the wire and decodes them back. All
+// watchers on a channel must use the same Serializer.
+type Serializer interface {
+	Marshal(m *MSG) ([]byte, error)
+	Unmarshal(data []byte, m *MSG) error
+}
+
+// JSONSerializer encodes messages as JSON. It is the default Serializer and
+// decodes every version from MinMSGVersion on.
+type JSONSerializer struct{}
+
+func (JSONSerializer) Marshal(m *MSG) ([]byte, error) {
+	return m.MarshalBinary()
+}
+
+func (JSONSerializer) Unmarshal(data []byte, m *MSG) error {
+	return m.UnmarshalBinary(data)
+}
+
+// customSerializer reports whether messages are encoded with something else
+// than JSON, which the pooled encoder and the update callbacks expect.
+func (w *Watcher) customSerializer() bool {
+	_, ok := w.options.Serializer.(JSONSerializer)
+	return !ok
+}
+
+// marshal encodes m with the configured Serializer.
+func (w *Watcher) marshal(m *MSG) ([]byte, error) {
+	return w.options.Serializer.Marshal(m)
+}
+
+// callbackPayload returns the payload passed to the update callbacks for a
+// message received as data. Callbacks always receive uncompressed JSON, so the
+// helpers of this package such as CustomDefaultFunc work with any Serializer
+// and Compressor.
+func (w *Watcher) callbackPayload(data string, msg *MSG, compressed bool) string {
+	if !compressed && !w.customSerializer() {
+		return data
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return data
+	}
+	return string(b)
+}
@@ -0,0 +1,35 @@
+package rediswatcher
+
+import "context"
+
+// SetChannel moves the watcher to another channel without recreating it:
+// it unsubscribes from the current channel, subscribes to channel and
+// publishes there from now on. channel is prefixed with the Namespace, if
+// any. The Redis clients and the callbacks are kept. Like Restart it waits
+// for the callbacks in progress, so it must not be called from a callback.
+//
+// The presence registration and the backlog follow the channel, while the
+// heartbeats stay on HeartbeatChannel. Channels and ChannelPattern are not
+// changed.
+func (w *Watcher) SetChannel(channel string) error {
+	channel, err := normalizeChannel(channel)
+	if err != nil {
+		return err
+	}
+	channel = namespaced(w.options.Namespace, channel)
+	if tagsChannel(&w.options) {
+		channel = hashTag(channel)
+	}
+	return w.restart(context.Background(), false, func() error {
+		if w.options.PresenceInterval > 0 {
+			if err := w.unregister(); err != nil {
+				w.reportError(err)
+			}
+		}
+		w.options.Channel = channel
+		if w.options.BacklogSize > 0 && w.subClient != nil {
+			return w.initSequence()
+		}
+		return nil
+	})
+}
@@ -0,0 +1,53 @@
+package rediswatcher
+
+import (
+	"errors"
+	"strings"
+
+	rds "github.com/redis/go-redis/v9"
+)
+
+// hashTag wraps channel in a Redis Cluster hash tag unless it already
+// contains one, so every client computes the same slot for it and keys
+// derived from it can be placed on the same shard.
+func hashTag(channel string) string {
+	if start := strings.IndexByte(channel, '{'); start >= 0 {
+		if end := strings.IndexByte(channel[start+1:], '}'); end > 0 {
+			return channel
+		}
+	}
+	return "{" + channel + "}"
+}
+
+// validateSharded checks that the options can be served by sharded pub/sub.
+func validateSharded(option *WatcherOptions) error {
+	if !option.ShardedPubSub {
+		return nil
+	}
+	if option.Transport == TransportStream {
+		return errors.New("sharded pub/sub is not supported with the stream transport")
+	}
+	if len(option.Channels) > 0 || option.ChannelPattern != "" || option.KeyspacePattern != "" {
+		return errors.New("sharded pub/sub supports a single channel only")
+	}
+	return nil
+}
+
+// tagsChannel reports whether the watcher channel is hash tagged: always
+// with sharded pub/sub, and in a cluster unless DisableHashTag is set.
+func tagsChannel(option *WatcherOptions) bool {
+	return option.ShardedPubSub || clustered(option) && !option.DisableHashTag
+}
+
+// clustered reports whether option connects to a Redis Cluster.
+func clustered(option *WatcherOptions) bool {
+	if len(option.ClusterAddrs) > 0 {
+		return true
+	}
+	for _, client := range []rds.UniversalClient{option.SubClient, option.PubClient} {
+		if _, ok := client.(*rds.ClusterClient); ok {
+			return true
+		}
+	}
+	return false
+}
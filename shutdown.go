@@ -0,0 +1,167 @@
+package rediswatcher
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// start runs f on a goroutine that Shutdown waits for. f must return once
+// w.close is closed.
+func (w *Watcher) start(f func()) {
+	w.loops.Add(1)
+	go func() {
+		defer w.loops.Done()
+		f()
+	}()
+}
+
+// Close stops the watcher like Shutdown, without a deadline and reporting
+// the error through the error callback. It is safe to call Close more than
+// once, only the first call has an effect.
+func (w *Watcher) Close() {
+	if err := w.Shutdown(context.Background()); err != nil {
+		w.reportError(err)
+	}
+}
+
+// Shutdown stops the watcher gracefully: it publishes the messages held
+// back or queued, notifies the peers, unsubscribes, waits for the messages
+// being handled and the callbacks queued for CallbackWorkers, then closes
+// the Redis clients. It returns the errors that occurred, or the error of
+// ctx if the callbacks did not complete before ctx was done. Only the first
+// call of Shutdown or Close has an effect, later calls return nil.
+//
+// Shutdown waits for the callbacks, so it must not be called from one.
+//
+// Watcher cannot implement io.Closer, as persist.Watcher requires a Close
+// method without result; Shutdown is its error returning counterpart.
+func (w *Watcher) Shutdown(ctx context.Context) error {
+	var errs []error
+	w.closeOnce.Do(func() {
+		w.restartL.Lock()
+		defer w.restartL.Unlock()
+		errs = w.stop(ctx)
+	})
+	return joinErrors(errs)
+}
+
+// stop implements Shutdown.
+func (w *Watcher) stop(ctx context.Context) []error {
+	var errs []error
+	w.l.Lock()
+	if err := w.flushPending(); err != nil {
+		errs = append(errs, err)
+	}
+	w.drainQueue()
+	if w.options.PresenceInterval > 0 {
+		// Unregister before the clients are closed, and keep the presence
+		// loop from registering again.
+		w.presenceL.Lock()
+		if err := w.unregister(); err != nil {
+			errs = append(errs, err)
+		}
+		close(w.close)
+		w.presenceL.Unlock()
+	} else {
+		close(w.close)
+	}
+	if w.options.Transport != TransportStream && w.options.Transport != TransportPoll && w.options.Mode != ModeSubOnly {
+		if err := w.sendClose(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, s := range w.subs {
+		s.close()
+	}
+	w.l.Unlock()
+	// Interrupt blocking reads of the stream transport.
+	w.cancel()
+	w.dropHeldReload()
+
+	if err := wait(ctx, &w.loops); err != nil {
+		errs = append(errs, err)
+	} else {
+		// Nothing is scheduled once the loops returned.
+		w.stopWorkers()
+		if err := wait(ctx, &w.workers); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	w.callbackCancel()
+
+	if w.subClient != nil && w.subClient != w.pubClient {
+		if err := w.subClient.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := w.pubClient.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, client := range w.backends {
+		if err := client.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	close(w.done)
+	return errs
+}
+
+// wait waits for wg, or returns the error of ctx if it is done first.
+func wait(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// multiError is the error of several failed operations.
+type multiError []error
+
+func (e multiError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// joinErrors returns nil, the only error of errs or a multiError.
+func joinErrors(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	default:
+		return multiError(errs)
+	}
+}
+
+// sendClose notifies the peers that the watcher closes, with a MethodClose
+// message or, for WireVersion 1, the raw "Close" payload.
+func (w *Watcher) sendClose() error {
+	if w.options.WireVersion < 2 {
+		return w.send(w.pubClient, w.options.Channel, "Close")
+	}
+	m := MSG{
+		Version:   w.options.WireVersion,
+		Method:    MethodClose,
+		ID:        w.options.LocalID,
+		Timestamp: time.Now().UnixMilli(),
+		Origin:    w.origin,
+	}
+	data, err := w.encode(&m)
+	if err != nil {
+		return err
+	}
+	return w.send(w.pubClient, w.options.Channel, data)
+}
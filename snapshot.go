@@ -0,0 +1,58 @@
+package rediswatcher
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const defaultSnapshotTTL = time.Hour
+
+// snapshotSeqKey is the counter versioning the snapshots stored for
+// channel.
+func snapshotSeqKey(channel string) string {
+	return hashTag(channel) + ":snapshot:seq"
+}
+
+// snapshotKey is the key of the snapshot of channel numbered version.
+func snapshotKey(channel string, version int64) string {
+	return hashTag(channel) + ":snapshot:" + strconv.FormatInt(version, 10)
+}
+
+// storeSnapshot moves the model of the UpdateForSavePolicy message m to a
+// new snapshot of channel, see WatcherOptions.SnapshotSavePolicy.
+func (w *Watcher) storeSnapshot(channel string, m *MSG) error {
+	data, err := w.encode(&MSG{Version: m.Version, Method: m.Method, ID: m.ID, Params: m.Params})
+	if err != nil {
+		return err
+	}
+	version, err := w.pubClient.Incr(w.ctx, snapshotSeqKey(channel)).Result()
+	if err != nil {
+		return err
+	}
+	key := snapshotKey(channel, version)
+	if err := w.pubClient.Set(w.ctx, key, data, w.options.SnapshotTTL).Err(); err != nil {
+		return err
+	}
+	m.Params = nil
+	m.Snapshot, m.SnapshotVersion = key, version
+	return nil
+}
+
+// loadSnapshot fetches the model of the message m from its snapshot.
+func (w *Watcher) loadSnapshot(m *MSG) error {
+	_, client := w.clients()
+	data, err := client.Get(w.ctx, m.Snapshot).Bytes()
+	if err != nil {
+		return fmt.Errorf("fetching snapshot %s of %s message from %s: %w", m.Snapshot, m.Method, m.ID, err)
+	}
+	snapshot := &MSG{}
+	if err := w.options.Serializer.Unmarshal(data, snapshot); err != nil {
+		return err
+	}
+	if err := w.open(snapshot); err != nil {
+		return err
+	}
+	m.Params = snapshot.Params
+	return nil
+}
@@ -0,0 +1,289 @@
+package rediswatcher
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	rds "github.com/redis/go-redis/v9"
+)
+
+// Transports supported by WatcherOptions.Transport.
+const (
+	// TransportPubSub delivers messages with PUBLISH/SUBSCRIBE. Subscribers
+	// that are disconnected miss the messages published in the meantime.
+	TransportPubSub = "pubsub"
+	// TransportStream appends messages to a Redis Stream named after the
+	// channel and reads them with XREAD, so a subscriber that reconnects
+	// continues from the last message it processed.
+	TransportStream = "stream"
+	// TransportPoll only bumps a version key for every message, which the
+	// subscribers poll every PollInterval, delivering an Update message
+	// when it changed. It works without pub/sub and long-lived connections,
+	// e.g. on managed Redis offerings restricting them, at the cost of the
+	// latency of the interval and of the message details.
+	TransportPoll = "poll"
+)
+
+const (
+	// defaultStreamMaxLen bounds the stream when StreamMaxLen is not set.
+	defaultStreamMaxLen = 1000
+	// streamReadCount is the number of entries fetched per XREAD, so a
+	// large backlog is replayed in batches.
+	streamReadCount = 100
+	// streamBlock is how long a single XREAD waits for new entries.
+	streamBlock = time.Second
+	// streamRetryDelay is the pause after a failed XREAD.
+	streamRetryDelay = time.Second
+	// streamField is the entry field holding the encoded message.
+	streamField = "msg"
+)
+
+// validateTransport checks the transport settings and fills in defaults.
+func validateTransport(option *WatcherOptions) error {
+	switch option.Transport {
+	case "", TransportPubSub, TransportPoll:
+		if option.StreamGroup != "" {
+			return errors.New("StreamGroup requires the stream transport")
+		}
+		return validatePoll(option)
+	case TransportStream:
+	default:
+		return errors.New("unsupported transport " + option.Transport)
+	}
+	if len(option.Backends) > 0 {
+		return errors.New("redundant backends are not supported with the stream transport")
+	}
+	if option.StreamRetention < 0 {
+		return errors.New("StreamRetention must not be negative")
+	}
+	if option.StreamMaxLen == 0 && option.StreamRetention == 0 {
+		option.StreamMaxLen = defaultStreamMaxLen
+	}
+	if option.StreamGroup != "" && option.StreamConsumer == "" {
+		option.StreamConsumer = option.LocalID
+	}
+	return validatePoll(option)
+}
+
+// send delivers payload on channel of client using the
+// configured transport.
+func (w *Watcher) send(client rds.UniversalClient, channel string, payload interface{}) error {
+	if w.options.Transport == TransportStream {
+		return w.appendStream(client, channel, payload)
+	}
+	if w.options.ShardedPubSub {
+		return client.SPublish(w.ctx, channel, payload).Err()
+	}
+	return client.Publish(w.ctx, channel, payload).Err()
+}
+
+// streamTrim returns how XADD trims the stream: to maxLen entries, to the
+// entries after minID, or not at all. As XADD trims by one strategy only,
+// trimMinID is the retention applied with XTRIM MINID after it when both
+// StreamMaxLen and StreamRetention are set.
+func (w *Watcher) streamTrim() (maxLen int64, minID, trimMinID string) {
+	if w.options.StreamRetention > 0 {
+		minID = strconv.FormatInt(time.Now().Add(-w.options.StreamRetention).UnixMilli(), 10)
+	}
+	if w.options.StreamMaxLen > 0 {
+		return w.options.StreamMaxLen, "", minID
+	}
+	return 0, minID, ""
+}
+
+// xtrimMinID trims the stream channel of client to the entries after
+// minID.
+func (w *Watcher) xtrimMinID(client rds.Cmdable, channel, minID string) *rds.IntCmd {
+	if w.options.StreamExactTrim {
+		return client.XTrimMinID(w.ctx, channel, minID)
+	}
+	return client.XTrimMinIDApprox(w.ctx, channel, minID, 0)
+}
+
+// appendStream appends payload to the stream channel of client, trimming
+// it to StreamMaxLen entries and to the entries added within
+// StreamRetention, in the same pipeline when both are set.
+func (w *Watcher) appendStream(client rds.UniversalClient, channel string, payload interface{}) error {
+	maxLen, minID, trimMinID := w.streamTrim()
+	args := &rds.XAddArgs{
+		Stream: channel,
+		MaxLen: maxLen,
+		MinID:  minID,
+		Approx: !w.options.StreamExactTrim,
+		Values: []interface{}{streamField, payload},
+	}
+	if trimMinID == "" {
+		return client.XAdd(w.ctx, args).Err()
+	}
+	_, err := client.Pipelined(w.ctx, func(pipe rds.Pipeliner) error {
+		pipe.XAdd(w.ctx, args)
+		w.xtrimMinID(pipe, channel, trimMinID)
+		return nil
+	})
+	return err
+}
+
+// sendCommand returns the command sending a payload, appended to it, on
+// channel of the main client, as send does, and for a stream trimmed both
+// by length and by age the trimming to run after it.
+func (w *Watcher) sendCommand(channel string) ([]interface{}, func()) {
+	switch {
+	case w.options.Transport == TransportStream:
+	case w.options.ShardedPubSub:
+		return []interface{}{"SPUBLISH", channel}, nil
+	default:
+		return []interface{}{"PUBLISH", channel}, nil
+	}
+	maxLen, minID, trimMinID := w.streamTrim()
+	strategy := "~"
+	if w.options.StreamExactTrim {
+		strategy = "="
+	}
+	command := []interface{}{"XADD", channel}
+	switch {
+	case maxLen > 0:
+		command = append(command, "MAXLEN", strategy, maxLen)
+	case minID != "":
+		command = append(command, "MINID", strategy, minID)
+	}
+	command = append(command, "*", streamField)
+	if trimMinID == "" {
+		return command, nil
+	}
+	return command, func() {
+		if err := w.xtrimMinID(w.pubClient, channel, trimMinID).Err(); err != nil {
+			w.logger().Warn(err)
+		}
+	}
+}
+
+// subscribeStream starts reading the watcher stream from StreamStartID, or
+// from its current end when no start is configured.
+func (w *Watcher) subscribeStream() error {
+	if w.options.StreamGroup != "" {
+		return w.subscribeStreamGroup()
+	}
+	lastID := w.options.StreamStartID
+	if lastID == "" || lastID == "$" {
+		// Resolve "$" once: passing it to every XREAD would skip entries
+		// added between two reads.
+		entries, err := w.subClient.XRevRangeN(w.ctx, w.options.Channel, "+", "-", 1).Result()
+		if err != nil {
+			return err
+		}
+		lastID = "0-0"
+		if len(entries) > 0 {
+			lastID = entries[0].ID
+		}
+	}
+
+	w.start(func() {
+		for {
+			streams, err := w.subClient.XRead(w.ctx, &rds.XReadArgs{
+				Streams: []string{w.options.Channel, lastID},
+				Count:   streamReadCount,
+				Block:   streamBlock,
+			}).Result()
+			select {
+			case <-w.close:
+				return
+			default:
+			}
+			if err == rds.Nil {
+				err = nil
+			}
+			w.errL.Lock()
+			w.streamErr = err
+			w.errL.Unlock()
+			if err != nil {
+				w.reportError(err)
+				select {
+				case <-w.close:
+					return
+				case <-time.After(streamRetryDelay):
+				}
+				continue
+			}
+			for _, stream := range streams {
+				for _, entry := range stream.Messages {
+					lastID = entry.ID
+					if data, ok := entry.Values[streamField].(string); ok {
+						w.dispatch(w.options.Channel, data)
+					}
+				}
+			}
+		}
+	})
+	return nil
+}
+
+// subscribeStreamGroup joins the consumer group StreamGroup, creating it at
+// StreamStartID if needed, and reads the entries of the stream assigned to
+// StreamConsumer with XREADGROUP, acknowledging each one once it was passed
+// to the callbacks. The entries still pending for the consumer, e.g. when
+// it stopped before acknowledging them, are read first.
+func (w *Watcher) subscribeStreamGroup() error {
+	start := w.options.StreamStartID
+	if start == "" {
+		start = "$"
+	}
+	err := w.subClient.XGroupCreateMkStream(w.ctx, w.options.Channel, w.options.StreamGroup, start).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+
+	w.start(func() {
+		// Read the pending entries until none is left, then the new ones.
+		lastID := "0"
+		for {
+			streams, err := w.subClient.XReadGroup(w.ctx, &rds.XReadGroupArgs{
+				Group:    w.options.StreamGroup,
+				Consumer: w.options.StreamConsumer,
+				Streams:  []string{w.options.Channel, lastID},
+				Count:    streamReadCount,
+				Block:    streamBlock,
+			}).Result()
+			select {
+			case <-w.close:
+				return
+			default:
+			}
+			if err == rds.Nil {
+				err = nil
+			}
+			w.errL.Lock()
+			w.streamErr = err
+			w.errL.Unlock()
+			if err != nil {
+				w.reportError(err)
+				select {
+				case <-w.close:
+					return
+				case <-time.After(streamRetryDelay):
+				}
+				continue
+			}
+			read := 0
+			for _, stream := range streams {
+				for _, entry := range stream.Messages {
+					read++
+					if lastID != ">" {
+						lastID = entry.ID
+					}
+					if data, ok := entry.Values[streamField].(string); ok {
+						w.dispatch(w.options.Channel, data)
+					}
+					if err := w.subClient.XAck(w.ctx, w.options.Channel, w.options.StreamGroup, entry.ID).Err(); err != nil && !w.closed() {
+						w.reportError(err)
+					}
+				}
+			}
+			if read == 0 {
+				lastID = ">"
+			}
+		}
+	})
+	return nil
+}
@@ -0,0 +1,214 @@
+package rediswatcher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// TenantWatcher publishes and receives the updates of one tenant on its own
+// channel, sharing the connections of the Watcher it was created from, see
+// Watcher.Tenant. It implements persist.Watcher and persist.WatcherEx, so
+// that it can be set as the watcher of the enforcer of the tenant.
+type TenantWatcher struct {
+	w        *Watcher
+	tenant   string
+	channel  string
+	callback func(string)
+	// subscribed receives a value every time Redis confirms a subscription
+	// to channel, see Watcher.tenantSubscribed.
+	subscribed chan struct{}
+}
+
+// tenantChannel is the channel of tenant.
+func tenantChannel(channel, tenant string) string {
+	return channel + ":tenant:" + tenant
+}
+
+// Tenant registers tenant and subscribes to its channel, the watcher
+// channel followed by ":tenant:" and the tenant ID, waiting until Redis
+// confirmed the subscription. The messages received on it are passed to the
+// update callback of the returned TenantWatcher only, rather than to the
+// handlers and callbacks of w; middlewares still apply.
+// Tenants are not supported with the stream or poll transports, sharded
+// pub/sub or a backlog, which use a single channel, nor with
+// WatcherOptions.PubSub.
+func (w *Watcher) Tenant(tenant string) (*TenantWatcher, error) {
+	if w.options.Transport == TransportStream || w.options.Transport == TransportPoll || w.options.ShardedPubSub || w.options.BacklogSize > 0 || w.options.PubSub != nil {
+		return nil, errors.New("tenants are not supported with the stream or poll transports, sharded pub/sub, a backlog or WatcherOptions.PubSub")
+	}
+	tenant = strings.TrimSpace(tenant)
+	if tenant == "" {
+		return nil, errors.New("tenant is empty")
+	}
+	w.l.Lock()
+	channel := tenantChannel(w.options.Channel, tenant)
+	subs := w.subs
+	w.l.Unlock()
+	t := &TenantWatcher{w: w, tenant: tenant, channel: channel, subscribed: make(chan struct{}, len(subs))}
+	if _, err := normalizeChannel(t.channel); err != nil {
+		return nil, err
+	}
+	_ = t.SetUpdateCallback(func(string) {
+		w.logger().Warn(fmt.Sprintf("Casbin Redis Watcher callback of tenant %s not set when an update was received", tenant))
+	})
+
+	w.tenantsL.Lock()
+	if _, ok := w.tenants[t.channel]; ok {
+		w.tenantsL.Unlock()
+		return nil, fmt.Errorf("tenant %s is already registered", tenant)
+	}
+	if w.tenants == nil {
+		w.tenants = make(map[string]*TenantWatcher)
+	}
+	w.tenants[t.channel] = t
+	w.tenantsL.Unlock()
+
+	for _, s := range subs {
+		if err := s.get().Subscribe(w.ctx, t.channel); err != nil {
+			t.Close()
+			return nil, err
+		}
+	}
+	// Wait for the confirmations so that the updates published once Tenant
+	// returns are not missed, as NewWatcher does.
+	timeout := time.After(healthCheckInterval)
+	for range subs {
+		select {
+		case <-t.subscribed:
+		case <-w.close:
+			t.Close()
+			return nil, ErrClosed
+		case <-timeout:
+			t.Close()
+			return nil, fmt.Errorf("timed out subscribing to %s", t.channel)
+		}
+	}
+	return t, nil
+}
+
+// tenantSubscribed is called when Redis confirms a subscription to channel.
+func (w *Watcher) tenantSubscribed(channel string) {
+	if t := w.tenantFor(channel); t != nil {
+		select {
+		case t.subscribed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// tenantChannels returns the channels of the registered tenants.
+func (w *Watcher) tenantChannels() []string {
+	w.tenantsL.Lock()
+	defer w.tenantsL.Unlock()
+	channels := make([]string, 0, len(w.tenants))
+	for channel := range w.tenants {
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+// tenantFor returns the tenant whose channel is channel, if any.
+func (w *Watcher) tenantFor(channel string) *TenantWatcher {
+	w.tenantsL.Lock()
+	defer w.tenantsL.Unlock()
+	return w.tenants[channel]
+}
+
+// Channel returns the channel of the tenant.
+func (t *TenantWatcher) Channel() string {
+	return t.channel
+}
+
+// SetUpdateCallback sets the callback receiving the messages of the tenant.
+func (t *TenantWatcher) SetUpdateCallback(callback func(string)) error {
+	t.w.tenantsL.Lock()
+	t.callback = callback
+	t.w.tenantsL.Unlock()
+	return nil
+}
+
+func (t *TenantWatcher) updateCallback() func(string) {
+	t.w.tenantsL.Lock()
+	defer t.w.tenantsL.Unlock()
+	return t.callback
+}
+
+// Close unregisters the tenant and unsubscribes from its channel. The
+// Watcher keeps running.
+func (t *TenantWatcher) Close() {
+	w := t.w
+	w.tenantsL.Lock()
+	delete(w.tenants, t.channel)
+	w.tenantsL.Unlock()
+	w.l.Lock()
+	subs := w.subs
+	w.l.Unlock()
+	for _, s := range subs {
+		if err := s.get().Unsubscribe(w.ctx, t.channel); err != nil && !w.closed() {
+			w.reportError(err)
+		}
+	}
+}
+
+// publish publishes m on the channel of the tenant.
+func (t *TenantWatcher) publish(m MSG) error {
+	m.channel = t.channel
+	return t.w.logRecord(func() error {
+		t.w.l.Lock()
+		defer t.w.l.Unlock()
+		if m.Method == MethodUpdateForSavePolicy && t.w.options.SavePolicyNotifyOnly {
+			m.Params = nil
+		}
+		return t.w.publish(m)
+	})
+}
+
+// Update asks the other watchers of the tenant to reload their policy.
+func (t *TenantWatcher) Update() error {
+	return t.publish(MSG{Method: MethodUpdate})
+}
+
+// UpdateForAddPolicy is the tenant counterpart of Watcher.UpdateForAddPolicy.
+func (t *TenantWatcher) UpdateForAddPolicy(sec, ptype string, params ...string) error {
+	return t.publish(MSG{Method: MethodUpdateForAddPolicy, Sec: sec, Ptype: ptype, Params: params})
+}
+
+// UpdateForRemovePolicy is the tenant counterpart of
+// Watcher.UpdateForRemovePolicy.
+func (t *TenantWatcher) UpdateForRemovePolicy(sec, ptype string, params ...string) error {
+	return t.publish(MSG{Method: MethodUpdateForRemovePolicy, Sec: sec, Ptype: ptype, Params: params})
+}
+
+// UpdateForRemoveFilteredPolicy is the tenant counterpart of
+// Watcher.UpdateForRemoveFilteredPolicy.
+func (t *TenantWatcher) UpdateForRemoveFilteredPolicy(sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	return t.publish(MSG{
+		Method:      MethodUpdateForRemoveFilteredPolicy,
+		Sec:         sec,
+		Ptype:       ptype,
+		Params:      fmt.Sprintf("%d %s", fieldIndex, strings.Join(fieldValues, " ")),
+		FieldIndex:  fieldIndex,
+		FieldValues: fieldValues,
+	})
+}
+
+// UpdateForSavePolicy is the tenant counterpart of Watcher.UpdateForSavePolicy.
+func (t *TenantWatcher) UpdateForSavePolicy(model model.Model) error {
+	return t.publish(MSG{Method: MethodUpdateForSavePolicy, Params: model})
+}
+
+// UpdateForAddPolicies is the tenant counterpart of
+// Watcher.UpdateForAddPolicies.
+func (t *TenantWatcher) UpdateForAddPolicies(sec, ptype string, rules [][]string) error {
+	return t.publish(MSG{Method: MethodUpdateForAddPolicies, Sec: sec, Ptype: ptype, Params: rules})
+}
+
+// UpdateForRemovePolicies is the tenant counterpart of
+// Watcher.UpdateForRemovePolicies.
+func (t *TenantWatcher) UpdateForRemovePolicies(sec, ptype string, rules [][]string) error {
+	return t.publish(MSG{Method: MethodUpdateForRemovePolicies, Sec: sec, Ptype: ptype, Params: rules})
+}
@@ -0,0 +1,45 @@
+package rediswatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrCallbackTimeout is reported through the error callback when handling a
+// message takes longer than WatcherOptions.CallbackTimeout.
+var ErrCallbackTimeout = errors.New("callback timed out")
+
+// SetUpdateCallbackWithContext sets an update callback that also receives
+// a context. The context is canceled once the watcher shut down, see
+// Shutdown, or after CallbackTimeout, so that a long-running reload can be
+// abandoned. It takes precedence over the callback set with
+// SetUpdateCallback.
+func (w *Watcher) SetUpdateCallbackWithContext(callback func(ctx context.Context, msg string)) error {
+	w.l.Lock()
+	w.contextCallback = callback
+	w.l.Unlock()
+	return nil
+}
+
+// callbackContext returns the context of the callbacks handling d, and
+// reports a timeout if they are still running after CallbackTimeout.
+func (w *Watcher) callbackContext(d *Delivery) (context.Context, func()) {
+	timeout := w.options.CallbackTimeout
+	if timeout <= 0 {
+		return w.callbackCtx, func() {}
+	}
+	ctx, cancel := context.WithTimeout(w.callbackCtx, timeout)
+	var method string
+	if d.MSG != nil {
+		method = d.MSG.Method
+	}
+	timer := time.AfterFunc(timeout, func() {
+		w.reportError(fmt.Errorf("%w: %q message on %s still handled after %s", ErrCallbackTimeout, method, d.Channel, timeout))
+	})
+	return ctx, func() {
+		timer.Stop()
+		cancel()
+	}
+}
@@ -0,0 +1,65 @@
+package rediswatcher
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// usesTLSFiles reports whether any of the TLS options building TLSConfig is
+// set.
+func usesTLSFiles(option *WatcherOptions) bool {
+	return option.TLSCertFile != "" || option.TLSKeyFile != "" || option.TLSCAFile != "" ||
+		option.TLSServerName != "" || option.TLSInsecureSkipVerify
+}
+
+// applyTLS sets up TLSConfig from the TLS options, on top of the TLSConfig
+// given, if any.
+func applyTLS(option *WatcherOptions) error {
+	if !usesTLSFiles(option) {
+		return nil
+	}
+	if option.DisableTLS {
+		return errors.New("DisableTLS cannot be combined with the TLS options")
+	}
+	if (option.TLSCertFile == "") != (option.TLSKeyFile == "") {
+		return errors.New("TLSCertFile and TLSKeyFile must be set together")
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if option.TLSConfig != nil {
+		config = option.TLSConfig.Clone()
+	}
+	if option.TLSCAFile != "" {
+		pem, err := os.ReadFile(option.TLSCAFile)
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificate found in TLSCAFile %s", option.TLSCAFile)
+		}
+		config.RootCAs = pool
+	}
+	if option.TLSCertFile != "" {
+		certFile, keyFile := option.TLSCertFile, option.TLSKeyFile
+		if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+			return err
+		}
+		// The pair is loaded for every handshake, so that a rotated
+		// certificate is used by the next connections.
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			return &cert, err
+		}
+	}
+	if option.TLSServerName != "" {
+		config.ServerName = option.TLSServerName
+	}
+	if option.TLSInsecureSkipVerify {
+		config.InsecureSkipVerify = true
+	}
+	option.TLSConfig = config
+	return nil
+}
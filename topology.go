@@ -0,0 +1,84 @@
+package rediswatcher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	rds "github.com/redis/go-redis/v9"
+)
+
+// defaultClusterRefreshInterval is the ClusterRefreshInterval of a watcher
+// subscribing through a cluster client when not set.
+const defaultClusterRefreshInterval = 10 * time.Second
+
+// clusterSubClient returns the cluster client the watcher subscribes with,
+// if any.
+func (w *Watcher) clusterSubClient() (*rds.ClusterClient, bool) {
+	client, ok := w.subClient.(*rds.ClusterClient)
+	return client, ok && w.options.Transport != TransportStream && w.options.Transport != TransportPoll
+}
+
+// watchTopology checks every ClusterRefreshInterval which node masters the
+// slot of the watcher channel, where the subscriptions are opened, and
+// re-creates them when it changed, e.g. after a slot migration or a node
+// was removed, instead of waiting for the subscription on the former
+// master to fail. The callbacks are kept.
+func (w *Watcher) watchTopology() {
+	client, _ := w.clusterSubClient()
+	interval := w.options.ClusterRefreshInterval
+	if interval == 0 {
+		interval = defaultClusterRefreshInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	master := ""
+	for {
+		if err := w.refreshTopology(client, &master); err != nil && !w.closed() {
+			w.reportError(err)
+		}
+		select {
+		case <-w.close:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshTopology looks up the master of the slot of the watcher channel
+// and, when it differs from the one in master, reloads the cluster state of
+// client and re-creates the subscriptions. It updates master.
+func (w *Watcher) refreshTopology(client *rds.ClusterClient, master *string) error {
+	slot, err := client.ClusterKeySlot(w.ctx, w.options.Channel).Result()
+	if err != nil {
+		return err
+	}
+	slots, err := client.ClusterSlots(w.ctx).Result()
+	if err != nil {
+		return err
+	}
+	current := ""
+	for _, s := range slots {
+		if int64(s.Start) <= slot && slot <= int64(s.End) && len(s.Nodes) > 0 {
+			current = s.Nodes[0].Addr
+			break
+		}
+	}
+	if current == "" {
+		return fmt.Errorf("no node serves slot %d of %s", slot, w.options.Channel)
+	}
+	previous := *master
+	*master = current
+	if previous == "" || previous == current {
+		return nil
+	}
+	w.logger().Info(fmt.Sprintf("slot %d of %s moved from %s to %s, resubscribing", slot, w.options.Channel, previous, current))
+	client.ReloadState(context.Background())
+	w.l.Lock()
+	subs := w.subs
+	w.l.Unlock()
+	for _, s := range subs {
+		s.reset()
+	}
+	return nil
+}
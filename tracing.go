@@ -0,0 +1,17 @@
+package rediswatcher
+
+// Tracer creates tracing spans around publishing and handling messages, e.g.
+// with OpenTelemetry. The trace context is carried inside the message, in the
+// form of a propagation carrier such as OpenTelemetry's
+// propagation.MapCarrier, so that a policy change can be followed from the
+// instance making it to every instance applying it.
+type Tracer interface {
+	// StartPublish starts a span for publishing a message of method. It
+	// returns the carrier to embed in the message and a function ending the
+	// span with the result of the publish.
+	StartPublish(method string) (carrier map[string]string, end func(err error))
+	// StartReceive starts a span for handling a received message of method,
+	// continuing the trace found in carrier, which is nil when the
+	// publisher did not trace it. It returns a function ending the span.
+	StartReceive(method string, carrier map[string]string) (end func())
+}
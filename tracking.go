@@ -0,0 +1,87 @@
+package rediswatcher
+
+import (
+	"context"
+	"fmt"
+
+	rds "github.com/redis/go-redis/v9"
+)
+
+// invalidateChannel is the channel Redis publishes the invalidation
+// messages of client tracking on for the RESP2 connections they are
+// redirected to.
+const invalidateChannel = "__redis__:invalidate"
+
+// trackingClient returns a client for the node holding the version key of
+// the watcher channel, each connection of which enables client tracking for
+// the key and redirects the invalidation messages to itself, so tracking is
+// enabled again whenever the subscription reconnects. The connections speak
+// RESP2, over which Redis sends the invalidations as messages on
+// invalidateChannel: over RESP3, the default of go-redis, they are push
+// messages that PubSub does not decode.
+func (w *Watcher) trackingClient() (*rds.Client, error) {
+	key := versionKey(w.options.Channel)
+	var node *rds.Client
+	switch client := w.subClient.(type) {
+	case *rds.Client:
+		node = client
+	case *rds.ClusterClient:
+		var err error
+		if node, err = client.MasterForKey(w.ctx, key); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("PollTracking is not supported with %T", w.subClient)
+	}
+	opt := *node.Options()
+	opt.Protocol = 2
+	onConnect := opt.OnConnect
+	opt.OnConnect = func(ctx context.Context, cn *rds.Conn) error {
+		if onConnect != nil {
+			if err := onConnect(ctx, cn); err != nil {
+				return err
+			}
+		}
+		id, err := cn.ClientID(ctx).Result()
+		if err != nil {
+			return err
+		}
+		cmd := rds.NewStatusCmd(ctx, "client", "tracking", "on", "redirect", id, "bcast", "prefix", key)
+		_ = cn.Process(ctx, cmd)
+		return cmd.Err()
+	}
+	return rds.NewClient(&opt), nil
+}
+
+// trackVersion subscribes to the invalidation messages of the version key
+// and signals pollNow for each of them, see WatcherOptions.PollTracking.
+// When tracking cannot be enabled, e.g. before Redis 6, it reports the error
+// and leaves the version to be read every PollInterval.
+func (w *Watcher) trackVersion() {
+	client, err := w.trackingClient()
+	if err != nil {
+		w.reportError(err)
+		return
+	}
+	defer client.Close()
+	sub := client.Subscribe(w.ctx, invalidateChannel)
+	defer sub.Close()
+	if _, err := sub.Receive(w.ctx); err != nil {
+		if !w.closed() {
+			w.reportError(err)
+		}
+		return
+	}
+	ch := sub.Channel()
+	for {
+		select {
+		case <-w.close:
+			return
+		case <-ch:
+			select {
+			case w.pollNow <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
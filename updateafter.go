@@ -0,0 +1,24 @@
+package rediswatcher
+
+import "fmt"
+
+// UpdateAfter calls write, typically the adapter write of a policy change,
+// and publishes an Update message only once it succeeded, so that the
+// subscribers never reload a policy that was not stored. It returns the
+// error of write, or the one of Update.
+//
+// When write fails and CompensateFailedWrites is set, an Update message is
+// published anyway as a compensation: the storage remains the reference,
+// and the instances reloading it drop the changes applied before the write
+// failed. A failed compensation is reported through the error callback.
+func (w *Watcher) UpdateAfter(write func() error) error {
+	if err := write(); err != nil {
+		if w.options.CompensateFailedWrites {
+			if cerr := w.Update(); cerr != nil {
+				w.reportError(fmt.Errorf("compensating a failed write: %w", cerr))
+			}
+		}
+		return err
+	}
+	return w.Update()
+}
@@ -21,20 +21,91 @@ func CustomDefaultFunc(defaultFunc func(string, interface{})) CallbackFunc {
 			f(msgStruct.ID, msgStruct.Params)
 		}
 		switch msgStruct.Method {
-		case "Update":
+		case MethodUpdate:
 			invoke(update)
-		case "UpdateForAddPolicy":
+		case MethodUpdateForAddPolicy:
 			invoke(updateForAddPolicy)
-		case "UpdateForRemovePolicy":
+		case MethodUpdateForRemovePolicy:
 			invoke(updateForRemovePolicy)
-		case "UpdateForRemoveFilteredPolicy":
+		case MethodUpdateForRemoveFilteredPolicy:
 			invoke(updateForRemoveFilteredPolicy)
-		case "UpdateForSavePolicy":
+		case MethodUpdateForSavePolicy:
 			invoke(updateForSavePolicy)
 		}
 	}
 }
 
+// UpdatableCallbackFunc dispatches UpdateForUpdatePolicy and
+// UpdateForUpdatePolicies messages, see CustomUpdatableFunc.
+type UpdatableCallbackFunc func(msg string, updateForUpdatePolicy, updateForUpdatePolicies func(string, interface{}))
+
+// CustomUpdatableFunc returns a dispatcher for the update methods of
+// persist.WatcherUpdatable. The UpdateForUpdatePolicy handler receives
+// [][]string{oldRule, newRule} and the UpdateForUpdatePolicies handler
+// receives [][][]string{oldRules, newRules}. Nil handlers fall back to
+// defaultFunc, messages of other methods are ignored.
+func CustomUpdatableFunc(defaultFunc func(string, interface{})) UpdatableCallbackFunc {
+	return func(msg string, updateForUpdatePolicy, updateForUpdatePolicies func(string, interface{})) {
+		msgStruct := &MSG{}
+		err := msgStruct.UnmarshalBinary([]byte(msg))
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		invoke := func(f func(string, interface{}), params interface{}) {
+			if f == nil {
+				f = defaultFunc
+			}
+			f(msgStruct.ID, params)
+		}
+		switch msgStruct.Method {
+		case MethodUpdateForUpdatePolicy:
+			if len(msgStruct.OldRules) != 1 || len(msgStruct.NewRules) != 1 {
+				log.Printf("malformed %s message", msgStruct.Method)
+				return
+			}
+			invoke(updateForUpdatePolicy, [][]string{msgStruct.OldRules[0], msgStruct.NewRules[0]})
+		case MethodUpdateForUpdatePolicies:
+			invoke(updateForUpdatePolicies, [][][]string{msgStruct.OldRules, msgStruct.NewRules})
+		}
+	}
+}
+
+// BatchCallbackFunc dispatches UpdateForAddPolicies and
+// UpdateForRemovePolicies messages, see CustomBatchFunc.
+type BatchCallbackFunc func(msg string, updateForAddPolicies, updateForRemovePolicies func(string, interface{}))
+
+// CustomBatchFunc returns a dispatcher for the batch update methods. The
+// handlers receive the rules of the batch as [][]string. Nil handlers fall
+// back to defaultFunc, messages of other methods are ignored.
+func CustomBatchFunc(defaultFunc func(string, interface{})) BatchCallbackFunc {
+	return func(msg string, updateForAddPolicies, updateForRemovePolicies func(string, interface{})) {
+		msgStruct := &MSG{}
+		err := msgStruct.UnmarshalBinary([]byte(msg))
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		invoke := func(f func(string, interface{})) {
+			rules, err := msgStruct.PoliciesParams()
+			if err != nil {
+				log.Println(err)
+				return
+			}
+			if f == nil {
+				f = defaultFunc
+			}
+			f(msgStruct.ID, rules)
+		}
+		switch msgStruct.Method {
+		case MethodUpdateForAddPolicies:
+			invoke(updateForAddPolicies)
+		case MethodUpdateForRemovePolicies:
+			invoke(updateForRemovePolicies)
+		}
+	}
+}
+
 func DefaultCallback(string) {
 }
 
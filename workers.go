@@ -0,0 +1,55 @@
+package rediswatcher
+
+import "hash/fnv"
+
+// defaultCallbackQueueSize is the CallbackQueueSize used when it is not set.
+const defaultCallbackQueueSize = 100
+
+// task is a message waiting for a callback worker.
+type task struct {
+	h Handler
+	d *Delivery
+}
+
+// startWorkers starts the callback workers when CallbackWorkers is set.
+func (w *Watcher) startWorkers() {
+	if w.options.CallbackWorkers <= 0 {
+		return
+	}
+	w.queues = make([]chan task, w.options.CallbackWorkers)
+	for i := range w.queues {
+		queue := make(chan task, w.options.CallbackQueueSize)
+		w.queues[i] = queue
+		w.workers.Add(1)
+		go func() {
+			defer w.workers.Done()
+			for t := range queue {
+				w.runTask(t)
+			}
+		}()
+	}
+}
+
+// stopWorkers lets the workers run the tasks still queued and stop. It must
+// only be called once nothing is scheduled anymore.
+func (w *Watcher) stopWorkers() {
+	for _, queue := range w.queues {
+		close(queue)
+	}
+}
+
+func (w *Watcher) runTask(t task) {
+	defer w.recoverCallback(t.d.Channel)
+	w.run(t.h, t.d)
+}
+
+// schedule queues d for the worker of its channel, so that the messages of a
+// channel are handled in order. It blocks while the queue is full.
+func (w *Watcher) schedule(h Handler, d *Delivery) {
+	f := fnv.New32a()
+	_, _ = f.Write([]byte(d.Channel))
+	select {
+	case w.queues[f.Sum32()%uint32(len(w.queues))] <- task{h: h, d: d}:
+	case <-w.close:
+	}
+}